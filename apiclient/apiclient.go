@@ -0,0 +1,84 @@
+// Package apiclient defines the subset of the serp-go client used by the
+// commands, so that alternate implementations such as fakes, recording
+// proxies or multi-tenant fan-out can be plugged into the command tree.
+//
+// Responses are the serp-go response types; commands hand them to the
+// printer as they are.
+package apiclient
+
+import (
+	"github.com/serptech/serp-go/api/client"
+	"github.com/serptech/serp-go/api/common"
+	"github.com/serptech/serp-go/api/entries"
+	"github.com/serptech/serp-go/api/origins"
+	"github.com/serptech/serp-go/api/profiles"
+	"github.com/serptech/serp-go/api/tokens"
+	"github.com/serptech/serp-go/api/users"
+	"github.com/serptech/serp-go/api/utility"
+)
+
+// Client groups the API services used by the commands.
+type Client interface {
+	Origins() OriginsService
+	Profiles() ProfilesService
+	Entries() EntriesService
+	Tokens() TokensService
+	Users() UsersService
+	Utility() UtilityService
+}
+
+// Factory creates clients. NewClient uses the default credentials
+// (SERP_ACCESS_TOKEN), NewClientWithToken an explicit token such as the root
+// token.
+type Factory interface {
+	NewClient() (Client, error)
+	NewClientWithToken(token string) Client
+}
+
+type OriginsService interface {
+	List(query common.Query) (*client.Object, error)
+	Get(id int) (*client.Object, error)
+	Create(req origins.CreateRequest) (*client.Object, error)
+	Update(req origins.UpdateRequest) (*client.Object, error)
+	Delete(id int) error
+}
+
+type ProfilesService interface {
+	Create(req profiles.CreateRequest) (*client.Object, error)
+	Search(req profiles.SearchRequest) (*client.Object, error)
+	Reinit(id string, req profiles.ReinitRequest) ([]client.Object, error)
+	Delete(id string) error
+}
+
+type EntriesService interface {
+	List(query common.Query) (*entries.ListResponse, error)
+	StatsSources(req entries.StatsSourcesRequest) (*client.Object, error)
+	Delete(id int) error
+}
+
+type TokensService interface {
+	ListAccess(query common.Query) (*client.Object, error)
+	CreateAccess(req tokens.CreateTokenRequest) (*client.Object, error)
+	DeleteAccess(key string) error
+	ListStreams(query common.Query) (*client.Object, error)
+	CreateStream(req tokens.CreateTokenRequest) (*client.Object, error)
+	DeleteStream(key string) error
+}
+
+type UsersService interface {
+	Me() (*client.Object, error)
+	Statistics() (*client.Object, error)
+	Version() (*client.Object, error)
+	List(query common.Query) (*client.Object, error)
+	Get(id int) (*client.Object, error)
+	Update(id int, req users.UpdateUserRequest) (*client.Object, error)
+	PartialUpdate(id int, req users.PartialUpdateUserRequest) (*client.Object, error)
+}
+
+type UtilityService interface {
+	Health() (*client.Object, error)
+	Metrics() (string, error)
+	Asm(req *utility.AsmRequest) (*client.Object, error)
+	Liveness(req *utility.LivenessRequest) (*client.Object, error)
+	Compare(req *utility.CompareRequest) (*client.Object, error)
+}
@@ -0,0 +1,175 @@
+package apiclient
+
+import (
+	"github.com/serptech/serp-go/api/client"
+	"github.com/serptech/serp-go/api/common"
+	"github.com/serptech/serp-go/api/entries"
+	"github.com/serptech/serp-go/api/origins"
+	"github.com/serptech/serp-go/api/profiles"
+	"github.com/serptech/serp-go/api/tokens"
+	"github.com/serptech/serp-go/api/users"
+	"github.com/serptech/serp-go/api/utility"
+)
+
+// Default creates clients backed by serp-go.
+var Default Factory = serpFactory{}
+
+type serpFactory struct{}
+
+func (serpFactory) NewClient() (Client, error) {
+	c, err := client.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	return Wrap(c), nil
+}
+
+func (serpFactory) NewClientWithToken(token string) Client {
+	return Wrap(client.NewClientWithToken(token))
+}
+
+// Wrap adapts a serp-go client to the Client interface.
+func Wrap(c *client.Client) Client {
+	return serpClient{c}
+}
+
+type serpClient struct{ c *client.Client }
+
+func (s serpClient) Origins() OriginsService   { return serpOrigins(s) }
+func (s serpClient) Profiles() ProfilesService { return serpProfiles(s) }
+func (s serpClient) Entries() EntriesService   { return serpEntries(s) }
+func (s serpClient) Tokens() TokensService     { return serpTokens(s) }
+func (s serpClient) Users() UsersService       { return serpUsers(s) }
+func (s serpClient) Utility() UtilityService   { return serpUtility(s) }
+
+type serpOrigins serpClient
+
+func (s serpOrigins) List(query common.Query) (*client.Object, error) {
+	return s.c.Origins().List(query)
+}
+
+func (s serpOrigins) Get(id int) (*client.Object, error) {
+	return s.c.Origins().Get(id)
+}
+
+func (s serpOrigins) Create(req origins.CreateRequest) (*client.Object, error) {
+	return s.c.Origins().Create(req)
+}
+
+func (s serpOrigins) Update(req origins.UpdateRequest) (*client.Object, error) {
+	return s.c.Origins().Update(req)
+}
+
+func (s serpOrigins) Delete(id int) error {
+	return s.c.Origins().Delete(id)
+}
+
+type serpProfiles serpClient
+
+func (s serpProfiles) Create(req profiles.CreateRequest) (*client.Object, error) {
+	return s.c.Profiles().Create(req)
+}
+
+func (s serpProfiles) Search(req profiles.SearchRequest) (*client.Object, error) {
+	return s.c.Profiles().Search(req)
+}
+
+func (s serpProfiles) Reinit(id string, req profiles.ReinitRequest) ([]client.Object, error) {
+	return s.c.Profiles().Reinit(id, req)
+}
+
+func (s serpProfiles) Delete(id string) error {
+	return s.c.Profiles().Delete(id)
+}
+
+type serpEntries serpClient
+
+func (s serpEntries) List(query common.Query) (*entries.ListResponse, error) {
+	return s.c.Entries().List(query)
+}
+
+func (s serpEntries) StatsSources(req entries.StatsSourcesRequest) (*client.Object, error) {
+	return s.c.Entries().StatsSources(req)
+}
+
+func (s serpEntries) Delete(id int) error {
+	return s.c.Entries().Delete(id)
+}
+
+type serpTokens serpClient
+
+func (s serpTokens) ListAccess(query common.Query) (*client.Object, error) {
+	return s.c.Tokens().ListAccess(query)
+}
+
+func (s serpTokens) CreateAccess(req tokens.CreateTokenRequest) (*client.Object, error) {
+	return s.c.Tokens().CreateAccess(req)
+}
+
+func (s serpTokens) DeleteAccess(key string) error {
+	return s.c.Tokens().DeleteAccess(key)
+}
+
+func (s serpTokens) ListStreams(query common.Query) (*client.Object, error) {
+	return s.c.Tokens().ListStreams(query)
+}
+
+func (s serpTokens) CreateStream(req tokens.CreateTokenRequest) (*client.Object, error) {
+	return s.c.Tokens().CreateStream(req)
+}
+
+func (s serpTokens) DeleteStream(key string) error {
+	return s.c.Tokens().DeleteStream(key)
+}
+
+type serpUsers serpClient
+
+func (s serpUsers) Me() (*client.Object, error) {
+	return s.c.Users().Me()
+}
+
+func (s serpUsers) Statistics() (*client.Object, error) {
+	return s.c.Users().Statistics()
+}
+
+func (s serpUsers) Version() (*client.Object, error) {
+	return s.c.Users().Version()
+}
+
+func (s serpUsers) List(query common.Query) (*client.Object, error) {
+	return s.c.Users().List(query)
+}
+
+func (s serpUsers) Get(id int) (*client.Object, error) {
+	return s.c.Users().Get(id)
+}
+
+func (s serpUsers) Update(id int, req users.UpdateUserRequest) (*client.Object, error) {
+	return s.c.Users().Update(id, req)
+}
+
+func (s serpUsers) PartialUpdate(id int, req users.PartialUpdateUserRequest) (*client.Object, error) {
+	return s.c.Users().PartialUpdate(id, req)
+}
+
+type serpUtility serpClient
+
+func (s serpUtility) Health() (*client.Object, error) {
+	return s.c.Utility().Health()
+}
+
+func (s serpUtility) Metrics() (string, error) {
+	return s.c.Utility().Metrics()
+}
+
+func (s serpUtility) Asm(req *utility.AsmRequest) (*client.Object, error) {
+	return s.c.Utility().Asm(req)
+}
+
+func (s serpUtility) Liveness(req *utility.LivenessRequest) (*client.Object, error) {
+	return s.c.Utility().Liveness(req)
+}
+
+func (s serpUtility) Compare(req *utility.CompareRequest) (*client.Object, error) {
+	return s.c.Utility().Compare(req)
+}
@@ -0,0 +1,325 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/serptech/serp-cli/transport"
+	"github.com/spf13/cobra"
+)
+
+var (
+	apiRawFields   []string
+	apiTypedFields []string
+	apiFiles       []string
+	apiHeaders     []string
+	apiInput       string
+	apiPaginate    bool
+	apiUseRoot     bool
+)
+
+func newAPICmd() *cobra.Command {
+	apiCmd := &cobra.Command{
+		Use:   "api METHOD PATH",
+		Short: "Make an authenticated request to any SERP API endpoint",
+		Long: `Sends a raw request to the configured base URL with the resolved token.
+PATH is relative to the base URL; a full URL must point at the same host.
+
+Fields given with -f/-F become query parameters for GET and DELETE requests
+and a JSON body otherwise. With --file the body is sent as multipart form data
+instead. JSON responses go through the regular output formatter.`,
+		Example: `  serptech api GET /v1/origins/ -f q=lobby
+  serptech api GET /v1/entries/ --paginate --format ndjson
+  serptech api PATCH /v1/origins/3/ -F is_active=false
+  serptech api POST /v1/profiles/ -F origin_id=42 --file photo=@img/profile.png
+  serptech api POST /v1/origins/ --input origin.json`,
+		Args: usageArgs(cobra.ExactArgs(2)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			method := strings.ToUpper(strings.TrimSpace(args[0]))
+			target, err := apiURL(args[1])
+			if err != nil {
+				return err
+			}
+
+			fields, err := apiFieldValues()
+			if err != nil {
+				return err
+			}
+			headers, err := parseAPIHeaders(apiHeaders)
+			if err != nil {
+				return err
+			}
+			token, err := apiToken()
+			if err != nil {
+				return err
+			}
+
+			if apiPaginate {
+				if method != http.MethodGet {
+					return usageErrorf("--paginate only works with GET requests")
+				}
+				fetchAll = true
+				return writeList(func(pageLimit, pageOffset int) (interface{}, error) {
+					page := map[string]interface{}{}
+					for key, value := range fields {
+						page[key] = value
+					}
+					page["limit"] = pageLimit
+					page["offset"] = pageOffset
+					return doAPIRequest(method, target, token, headers, page)
+				})
+			}
+
+			resp, err := doAPIRequest(method, target, token, headers, fields)
+			if err != nil {
+				return err
+			}
+			if raw, ok := resp.([]byte); ok {
+				if len(raw) > 0 {
+					_, err = stdout.Write(raw)
+				}
+				return err
+			}
+			return writeOutput(resp)
+		},
+	}
+
+	apiCmd.Flags().StringArrayVarP(&apiRawFields, "raw-field", "f", nil, "add a string parameter in key=value format")
+	apiCmd.Flags().StringArrayVarP(&apiTypedFields, "field", "F", nil, "add a typed parameter in key=value format (numbers, true, false and null are converted)")
+	apiCmd.Flags().StringArrayVar(&apiFiles, "file", nil, "attach a file as multipart form field in name=@path format")
+	apiCmd.Flags().StringArrayVarP(&apiHeaders, "header", "H", nil, "add an HTTP request header in key:value format")
+	apiCmd.Flags().StringVar(&apiInput, "input", "", "file to use as the request body (use \"-\" for stdin)")
+	apiCmd.Flags().BoolVar(&apiPaginate, "paginate", false, "walk every page with limit/offset and output the merged results")
+	apiCmd.Flags().BoolVar(&apiUseRoot, "root", false, "authenticate with the root token instead of the access token")
+
+	return apiCmd
+}
+
+// apiURL resolves path against the base URL. An absolute URL is only
+// accepted on the host of the base URL, so the token is never sent elsewhere.
+func apiURL(path string) (string, error) {
+	base := strings.TrimRight(displayBaseURL(strings.TrimSpace(os.Getenv("SERP_BASE_URL"))), "/")
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		return base + "/" + strings.TrimLeft(path, "/"), nil
+	}
+	target, err := url.Parse(path)
+	if err != nil {
+		return "", usageErrorf("invalid path: %v", err)
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", usageErrorf("invalid base URL %q: %v", base, err)
+	}
+	if !strings.EqualFold(target.Scheme, baseURL.Scheme) || !strings.EqualFold(target.Host, baseURL.Host) {
+		return "", usageErrorf("refusing to send credentials to %s://%s, which is not the base URL %s", target.Scheme, target.Host, base)
+	}
+	return path, nil
+}
+
+func apiToken() (string, error) {
+	tok, err := resolveToken(requiredToken)
+	if err != nil {
+		return "", err
+	}
+	if tok.token == "" {
+		return "", authErrorf("api needs an access token: pass --token, set SERP_ACCESS_TOKEN or run serptech login")
+	}
+	return tok.token, nil
+}
+
+func apiFieldValues() (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	for _, raw := range apiRawFields {
+		key, value, ok := strings.Cut(raw, "=")
+		if !ok || key == "" {
+			return nil, usageErrorf("invalid field %q, expected key=value", raw)
+		}
+		fields[key] = value
+	}
+	for _, raw := range apiTypedFields {
+		key, value, ok := strings.Cut(raw, "=")
+		if !ok || key == "" {
+			return nil, usageErrorf("invalid field %q, expected key=value", raw)
+		}
+		fields[key] = typedFieldValue(value)
+	}
+	return fields, nil
+}
+
+func typedFieldValue(value string) interface{} {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+func parseAPIHeaders(values []string) (http.Header, error) {
+	headers := http.Header{}
+	for _, raw := range values {
+		key, value, ok := strings.Cut(raw, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, usageErrorf("invalid header %q, expected key:value", raw)
+		}
+		headers.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+// doAPIRequest sends the request and returns the decoded JSON response, or
+// the raw bytes when the response is not JSON.
+func doAPIRequest(method, target, token string, headers http.Header, fields map[string]interface{}) (interface{}, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, usageErrorf("invalid path: %v", err)
+	}
+
+	var (
+		body        io.Reader
+		contentType string
+	)
+	switch {
+	case apiInput != "":
+		if len(fields) > 0 && method != http.MethodGet && method != http.MethodDelete {
+			return nil, usageErrorf("--input cannot be combined with fields for %s requests", method)
+		}
+		data, err := readAPIInput(apiInput)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	case len(apiFiles) > 0:
+		body, contentType, err = multipartBody(fields)
+		if err != nil {
+			return nil, err
+		}
+		fields = nil
+	case len(fields) > 0 && method != http.MethodGet && method != http.MethodDelete:
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+		fields = nil
+	}
+
+	if len(fields) > 0 {
+		query := u.Query()
+		for key, value := range fields {
+			if value == nil {
+				continue
+			}
+			query.Set(key, fmt.Sprint(value))
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, usageErrorf("invalid request: %v", err)
+	}
+	req.Header.Set("Authorization", "Token "+token)
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for key, values := range headers {
+		req.Header[key] = values
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, &transport.ExchangeError{
+			Exchange: transport.FailedExchange(req, resp, data),
+			Err:      fmt.Errorf("%s %s: %s: %s", method, u.Path, resp.Status, strings.TrimSpace(string(data))),
+		}
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return data, nil
+	}
+	if !strings.Contains(resp.Header.Get("Content-Type"), "json") && !json.Valid(data) {
+		return data, nil
+	}
+	var decoded interface{}
+	if err := unmarshalNumbers(data, &decoded); err != nil {
+		return data, nil
+	}
+	return decoded, nil
+}
+
+func readAPIInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(stdin)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read input: %w", err)
+	}
+	return data, nil
+}
+
+// multipartBody builds a form with the fields and every --file attachment.
+func multipartBody(fields map[string]interface{}) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for key, value := range fields {
+		if value == nil {
+			continue
+		}
+		if err := w.WriteField(key, fmt.Sprint(value)); err != nil {
+			return nil, "", err
+		}
+	}
+	for _, raw := range apiFiles {
+		name, path, ok := strings.Cut(raw, "=")
+		path = strings.TrimPrefix(path, "@")
+		if !ok || name == "" || path == "" {
+			return nil, "", usageErrorf("invalid file %q, expected name=@path", raw)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", fmt.Errorf("read %s: %w", path, err)
+		}
+		part, err := w.CreateFormFile(name, filepath.Base(path))
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, w.FormDataContentType(), nil
+}
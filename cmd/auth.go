@@ -0,0 +1,505 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/serptech/serp-cli/config"
+	"github.com/serptech/serp-cli/credential"
+	"github.com/serptech/serp-go/api/client"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// defaultLoginContext names the logins made while no context is selected.
+const defaultLoginContext = "default"
+
+var (
+	loginRoot      bool
+	loginWithToken bool
+	logoutAll      bool
+)
+
+// authStatusView is one row of auth status: a token a context resolves to.
+type authStatusView struct {
+	Context  string `json:"context"`
+	Current  bool   `json:"current"`
+	BaseURL  string `json:"base_url"`
+	Kind     string `json:"kind,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Identity string `json:"identity,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func newLoginCmd() *cobra.Command {
+	loginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Validate a token and save it encrypted for the current context",
+		Long: `Checks a token against the API and saves it in a passphrase-encrypted file
+next to the config file, under the current context or "default" when none is
+selected. Commands using that context then find the token there whenever the
+flags, the environment, the config file and its credential helper do not
+provide one.
+
+The token is read from --token or --root-token, from the first line of stdin
+with --with-token, or prompted for. The passphrase comes from SERP_PASSPHRASE
+or is prompted for.`,
+		Example: `  serptech login
+  serptech --context prod login --root
+  vault read -field=token secret/serp | serptech login --with-token`,
+		Annotations: map[string]string{"credentials": "manual"},
+		Args:        usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadDefault()
+			if err != nil {
+				return err
+			}
+			name, explicit := selectedContext(cfg)
+			base := strings.TrimSpace(os.Getenv("SERP_BASE_URL"))
+			if name != "" {
+				ctx, err := cfg.Context(name)
+				switch {
+				case err == nil && ctx.BaseURL != "" && (explicit || base == ""):
+					base = ctx.BaseURL
+				case err != nil && explicit:
+					return &usageError{msg: err.Error()}
+				}
+			}
+			key := loginContext(name)
+
+			kind, token := "access", flagAccessToken
+			if loginRoot {
+				kind, token = "root", flagRootToken
+			}
+			if token == "" {
+				if token, err = readToken(kind); err != nil {
+					return err
+				}
+			}
+
+			var me *client.Object
+			err = withBaseURL(base, func() error {
+				me, err = clients.NewClientWithToken(token).Users().Me()
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("validate %s token: %w", kind, err)
+			}
+			identity := identityOf(me)
+
+			path := vaultPath(cfg)
+			_, statErr := os.Stat(path)
+			passphrase, err := readPassphrase(errors.Is(statErr, os.ErrNotExist))
+			if err != nil {
+				return err
+			}
+			vault, err := credential.OpenVault(path, passphrase)
+			if err != nil {
+				return &authError{msg: err.Error()}
+			}
+			vault.Set(key, credential.Login{
+				BaseURL:  base,
+				Token:    token,
+				Root:     loginRoot,
+				Identity: identity,
+				SavedAt:  time.Now().UTC(),
+			})
+			if err := vault.Save(); err != nil {
+				return fmt.Errorf("save credentials: %w", err)
+			}
+			fmt.Fprintf(stdout, "logged in to %s as %s; %s token for context %q saved to %s\n",
+				displayBaseURL(base), identity, kind, key, path)
+			return nil
+		},
+	}
+
+	loginCmd.Flags().BoolVar(&loginRoot, "root", false, "save a root token instead of an access token")
+	loginCmd.Flags().BoolVar(&loginWithToken, "with-token", false, "read the token from stdin")
+	return loginCmd
+}
+
+func newLogoutCmd() *cobra.Command {
+	logoutCmd := &cobra.Command{
+		Use:         "logout",
+		Short:       "Remove the tokens saved with login for the current context",
+		Annotations: map[string]string{"credentials": "manual"},
+		Args:        usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadDefault()
+			if err != nil {
+				return err
+			}
+			path := vaultPath(cfg)
+			if logoutAll {
+				if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+				fmt.Fprintf(stdout, "removed all saved logins\n")
+				return nil
+			}
+
+			name, _ := selectedContext(cfg)
+			key := loginContext(name)
+			names, err := credential.VaultContexts(path)
+			if err != nil {
+				return err
+			}
+			if !contains(names, key) {
+				fmt.Fprintf(stdout, "not logged in for context %q\n", key)
+				return nil
+			}
+			if len(names) == 1 {
+				// Nothing else is in the file, so it can go without unlocking it.
+				if err := os.Remove(path); err != nil {
+					return err
+				}
+			} else {
+				passphrase, err := readPassphrase(false)
+				if err != nil {
+					return err
+				}
+				vault, err := credential.OpenVault(path, passphrase)
+				if err != nil {
+					return &authError{msg: err.Error()}
+				}
+				vault.Delete(key)
+				if err := vault.Save(); err != nil {
+					return fmt.Errorf("save credentials: %w", err)
+				}
+			}
+			fmt.Fprintf(stdout, "logged out of context %q\n", key)
+			return nil
+		},
+	}
+
+	logoutCmd.Flags().BoolVar(&logoutAll, "all", false, "remove the saved tokens of every context")
+	return logoutCmd
+}
+
+func newAuthStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the identity, base URL and token kind each context resolves to",
+		Long: `Resolves the tokens of every context the way other commands do and checks
+each of them against the API. The source column tells where a token came
+from: flag, environment, config, credential helper or login.`,
+		Args: usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadDefault()
+			if err != nil {
+				return err
+			}
+			current, _ := selectedContext(cfg)
+			currentKey := loginContext(current)
+
+			path := vaultPath(cfg)
+			saved, err := credential.VaultContexts(path)
+			if err != nil {
+				return err
+			}
+			names := cfg.Names()
+			for _, name := range append(saved, currentKey) {
+				if !contains(names, name) {
+					names = append(names, name)
+				}
+			}
+
+			var vault *credential.Vault
+			var vaultErr error
+			if len(saved) > 0 {
+				var passphrase []byte
+				if passphrase, vaultErr = readPassphrase(false); vaultErr == nil {
+					vault, vaultErr = credential.OpenVault(path, passphrase)
+				}
+			}
+
+			var views []authStatusView
+			failed := 0
+			for _, name := range names {
+				rows := authStatus(cmd, cfg, name, name == currentKey, vault, vaultErr, contains(saved, name))
+				for _, row := range rows {
+					if row.Error != "" {
+						failed++
+					}
+				}
+				views = append(views, rows...)
+			}
+			if err := writeOutput(views); err != nil {
+				return err
+			}
+			if failed > 0 {
+				return authErrorf("%d of %d credentials could not be verified", failed, len(views))
+			}
+			return nil
+		},
+	}
+}
+
+// authToken is a token found for a context and where it came from.
+type authToken struct {
+	kind, token, source, baseURL string
+}
+
+// authStatus resolves and verifies the tokens of the named context.
+func authStatus(cmd *cobra.Command, cfg *config.Config, name string, current bool, vault *credential.Vault, vaultErr error, saved bool) []authStatusView {
+	ctx, ok := cfg.Contexts[name]
+	if !ok {
+		ctx = &config.Context{}
+	}
+	base := ctx.BaseURL
+	if env := strings.TrimSpace(os.Getenv("SERP_BASE_URL")); current && env != "" {
+		base = env
+	}
+
+	var found []authToken
+	add := func(kind, token, source, baseURL string) {
+		for _, f := range found {
+			if f.kind == kind {
+				return
+			}
+		}
+		if token != "" {
+			found = append(found, authToken{kind: kind, token: token, source: source, baseURL: baseURL})
+		}
+	}
+	var errs []string
+	if current {
+		add("access", flagAccessToken, "flag", base)
+		add("root", flagRootToken, "flag", base)
+		add("access", os.Getenv("SERP_ACCESS_TOKEN"), settingSource("SERP_ACCESS_TOKEN"), base)
+		add("root", os.Getenv("SERP_ROOT_TOKEN"), settingSource("SERP_ROOT_TOKEN"), base)
+	}
+	add("access", ctx.AccessToken, "config", base)
+	add("root", ctx.RootToken, "config", base)
+	if ctx.CredentialHelper != "" {
+		helper := credential.Helper{Command: ctx.CredentialHelper}
+		creds, err := helper.Get(cmd.Context(), credentialRequest(name, ctx))
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		add("access", creds.AccessToken, "credential helper", base)
+		add("root", creds.RootToken, "credential helper", base)
+	}
+	switch {
+	case vault != nil:
+		for _, login := range vault.Logins(name) {
+			loginBase := base
+			if loginBase == "" {
+				loginBase = login.BaseURL
+			}
+			kind := "access"
+			if login.Root {
+				kind = "root"
+			}
+			add(kind, login.Token, "login", loginBase)
+		}
+	case saved && vaultErr != nil:
+		errs = append(errs, "saved login: "+vaultErr.Error())
+	}
+
+	if len(found) == 0 {
+		msg := "no credentials"
+		if len(errs) > 0 {
+			msg = strings.Join(errs, "; ")
+		}
+		return []authStatusView{{Context: name, Current: current, BaseURL: displayBaseURL(base), Error: msg}}
+	}
+	views := make([]authStatusView, 0, len(found))
+	for _, f := range found {
+		view := authStatusView{Context: name, Current: current, BaseURL: displayBaseURL(f.baseURL), Kind: f.kind, Source: f.source}
+		var me *client.Object
+		err := withBaseURL(f.baseURL, func() error {
+			var err error
+			me, err = clients.NewClientWithToken(f.token).Users().Me()
+			return err
+		})
+		if err != nil {
+			view.Error = err.Error()
+		} else {
+			view.Identity = identityOf(me)
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+func newAuthCmd() *cobra.Command {
+	authCmd := &cobra.Command{
+		Use:         "auth",
+		Short:       "Inspect the credentials used by each context",
+		Annotations: map[string]string{"credentials": "manual", "resource": "auth"},
+		Example: `  serptech login
+  serptech auth status --format table
+  serptech logout`,
+	}
+
+	authCmd.AddCommand(newAuthStatusCmd())
+	return authCmd
+}
+
+// applyLogin exports the tokens of the kinds needed that were saved with
+// login for the named context. The credential file is only unlocked when it
+// holds something for the context.
+func applyLogin(cfg *config.Config, name string, needAccess, needRoot bool) error {
+	path := vaultPath(cfg)
+	names, err := credential.VaultContexts(path)
+	if err != nil || !contains(names, name) {
+		return err
+	}
+
+	passphrase, err := readPassphrase(false)
+	if err != nil {
+		return err
+	}
+	vault, err := credential.OpenVault(path, passphrase)
+	if err != nil {
+		return &authError{msg: fmt.Sprintf("unlock %s: %v", path, err)}
+	}
+	var access, root, base string
+	for _, login := range vault.Logins(name) {
+		if login.Root {
+			root = login.Token
+		} else {
+			access = login.Token
+		}
+		if base == "" {
+			base = login.BaseURL
+		}
+	}
+	if fromDotenv("SERP_BASE_URL") {
+		os.Unsetenv("SERP_BASE_URL")
+		delete(settingSources, "SERP_BASE_URL")
+	}
+	if base != "" && os.Getenv("SERP_BASE_URL") == "" && baseURL == "" {
+		if err := setSetting("SERP_BASE_URL", base, "login"); err != nil {
+			return err
+		}
+	}
+	return exportTokens("login", needAccess, access, needRoot, root)
+}
+
+// loginContext is the name logins for the selected context are saved under.
+func loginContext(name string) string {
+	if name == "" {
+		return defaultLoginContext
+	}
+	return name
+}
+
+// vaultPath returns the credential file kept next to the config file.
+func vaultPath(cfg *config.Config) string {
+	return filepath.Join(filepath.Dir(cfg.Path()), credential.VaultFile)
+}
+
+// readPassphrase returns SERP_PASSPHRASE or prompts for the passphrase of the
+// credential file, twice when it is about to be created.
+func readPassphrase(confirm bool) ([]byte, error) {
+	if p := os.Getenv("SERP_PASSPHRASE"); p != "" {
+		return []byte(p), nil
+	}
+	f, ok := stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return nil, authErrorf("set SERP_PASSPHRASE or run in a terminal to unlock the credential file")
+	}
+	passphrase, err := promptSecret(f, "Passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, authErrorf("the passphrase must not be empty")
+	}
+	if confirm {
+		again, err := promptSecret(f, "Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if string(again) != string(passphrase) {
+			return nil, authErrorf("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+// readToken reads the token to log in with from stdin or a prompt.
+func readToken(kind string) (string, error) {
+	if loginWithToken {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		if token := strings.TrimSpace(line); token != "" {
+			return token, nil
+		}
+		return "", usageErrorf("no token on stdin")
+	}
+	f, ok := stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return "", usageErrorf("pass the token with --with-token on stdin or run in a terminal")
+	}
+	token, err := promptSecret(f, fmt.Sprintf("SERP %s token: ", kind))
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(string(token)) == "" {
+		return "", usageErrorf("the token must not be empty")
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+func promptSecret(f *os.File, prompt string) ([]byte, error) {
+	fmt.Fprint(stderr, prompt)
+	secret, err := term.ReadPassword(int(f.Fd()))
+	fmt.Fprintln(stderr)
+	return secret, err
+}
+
+// withBaseURL runs fn with SERP_BASE_URL set to base, or unset when base is
+// empty, because the client reads the base URL from the environment.
+func withBaseURL(base string, fn func() error) error {
+	prev, had := os.LookupEnv("SERP_BASE_URL")
+	defer func() {
+		if had {
+			os.Setenv("SERP_BASE_URL", prev)
+		} else {
+			os.Unsetenv("SERP_BASE_URL")
+		}
+	}()
+	if base == "" {
+		os.Unsetenv("SERP_BASE_URL")
+	} else {
+		os.Setenv("SERP_BASE_URL", base)
+	}
+	return fn()
+}
+
+func displayBaseURL(base string) string {
+	if base == "" {
+		return client.DefaultBaseURL
+	}
+	return base
+}
+
+// identityOf names the user a users/me response describes.
+func identityOf(me *client.Object) string {
+	if me != nil {
+		if id := firstValue(*me, "username", "email", "id"); id != "" {
+			return id
+		}
+	}
+	return "unknown"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/serptech/serp-cli/apiclient"
+	"github.com/serptech/serp-cli/config"
+	"github.com/serptech/serp-cli/credential"
+	"github.com/serptech/serp-cli/redact"
+	cliutils "github.com/serptech/serp-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+// tokenNeed is the kind of token a command authenticates with.
+type tokenNeed int
+
+const (
+	// needAccess takes an access token, or the root token in its place with
+	// --allow-root-fallback.
+	needAccess tokenNeed = iota
+	// needAccessOnly takes an access token; the API does not accept the root
+	// token there.
+	needAccessOnly
+	// needRoot takes the root token.
+	needRoot
+	// needNone is for public endpoints and commands that call no API; any
+	// token configured is sent, but none is looked up.
+	needNone
+)
+
+// tokenNeeds maps command paths below serptech, followed by the action for
+// commands that take one, to the token they need. Commands missing here need
+// an access token.
+var tokenNeeds = map[string]tokenNeed{
+	"users list":      needRoot,
+	"users get":       needRoot,
+	"users update":    needRoot,
+	"users patch":     needRoot,
+	"utility compare": needAccessOnly,
+	"utility health":  needNone,
+	"env":             needNone,
+	"replay":          needNone,
+}
+
+// tokenLookup is where a command looks for tokens that the flags, the
+// environment and the config file do not provide: the credential helper of
+// the selected context, then the tokens saved with serptech login.
+type tokenLookup struct {
+	ctx     context.Context
+	cfg     *config.Config
+	name    string
+	context *config.Context
+}
+
+var (
+	allowRootFallback bool
+
+	// pendingLookup is set up by applyContext and consumed by the first
+	// token resolution, so commands that need no token never run a helper
+	// or ask for a passphrase.
+	pendingLookup *tokenLookup
+
+	// tokenCommand and requiredToken describe the running command; they are
+	// set before it runs.
+	tokenCommand  string
+	requiredToken tokenNeed
+)
+
+// resolvedToken is the token chosen for a command and where it came from.
+type resolvedToken struct {
+	kind   string
+	token  string
+	source string
+}
+
+// setTokenNeed looks up the token cmd needs for action.
+func setTokenNeed(cmd *cobra.Command, action string) {
+	tokenCommand = strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	if action != "" {
+		tokenCommand += " " + action
+	}
+	requiredToken = tokenNeeds[tokenCommand]
+	if tokenCommand == "api" && apiUseRoot {
+		requiredToken = needRoot
+	}
+}
+
+// resolveToken picks the token for need from the settings resolved for the
+// command: flags, environment, env files, the context, its credential helper
+// and saved logins. An access token is never silently replaced by the root
+// token. An empty token means no access token is configured and leaves the
+// decision to the client factory.
+func resolveToken(need tokenNeed) (resolvedToken, error) {
+	if err := lookupTokens(need); err != nil {
+		return resolvedToken{}, err
+	}
+	access := strings.TrimSpace(os.Getenv("SERP_ACCESS_TOKEN"))
+	root := strings.TrimSpace(os.Getenv("SERP_ROOT_TOKEN"))
+	rootSource := settingSource("SERP_ROOT_TOKEN")
+
+	if need == needRoot {
+		if root == "" {
+			return resolvedToken{}, authErrorf("%s needs a root token: pass --root-token, set SERP_ROOT_TOKEN or run serptech login --root", tokenCommand)
+		}
+		return resolvedToken{kind: "root", token: root, source: rootSource}, nil
+	}
+	if access != "" || root == "" {
+		return resolvedToken{kind: "access", token: access, source: settingSource("SERP_ACCESS_TOKEN")}, nil
+	}
+	if need == needNone {
+		return resolvedToken{kind: "root", token: root, source: rootSource}, nil
+	}
+	if need == needAccessOnly {
+		return resolvedToken{}, authErrorf("%s needs an access token and does not accept the root token: pass --token, set SERP_ACCESS_TOKEN or run serptech login", tokenCommand)
+	}
+	if !allowRootFallback {
+		return resolvedToken{}, authErrorf("%s needs an access token; the root token from %s is only used in its place with --allow-root-fallback", tokenCommand, rootSource)
+	}
+	cliutils.Warn().Str("source", rootSource).Msgf("%s: no access token, using the root token", tokenCommand)
+	return resolvedToken{kind: "root", token: root, source: rootSource}, nil
+}
+
+// missingTokens reports which of the tokens usable for need are still unset.
+func missingTokens(need tokenNeed) (access, root bool) {
+	switch need {
+	case needAccess:
+		access, root = true, allowRootFallback
+	case needAccessOnly:
+		access = true
+	case needRoot:
+		root = true
+	}
+	access = access && os.Getenv("SERP_ACCESS_TOKEN") == ""
+	root = root && os.Getenv("SERP_ROOT_TOKEN") == ""
+	return access, root
+}
+
+// lookupTokens fills in the missing tokens usable for need from the pending
+// lookup, asking the credential helper before the saved logins.
+func lookupTokens(need tokenNeed) error {
+	lookup := pendingLookup
+	needAccess, needRoot := missingTokens(need)
+	if lookup == nil || !needAccess && !needRoot {
+		return nil
+	}
+	pendingLookup = nil
+
+	if c := lookup.context; c != nil && c.CredentialHelper != "" {
+		helper := credential.Helper{Command: c.CredentialHelper}
+		creds, err := helper.Get(lookup.ctx, credentialRequest(lookup.name, c))
+		if err != nil {
+			return &authError{msg: err.Error()}
+		}
+		if err := exportTokens("credential helper", needAccess, creds.AccessToken, needRoot, creds.RootToken); err != nil {
+			return err
+		}
+		needAccess, needRoot = missingTokens(need)
+	}
+	if needAccess || needRoot {
+		if err := applyLogin(lookup.cfg, loginContext(lookup.name), needAccess, needRoot); err != nil {
+			return err
+		}
+	}
+	redact.AddSecret(os.Getenv("SERP_ACCESS_TOKEN"), os.Getenv("SERP_ROOT_TOKEN"))
+	return nil
+}
+
+// newClient builds a client authenticated with the token the running command
+// needs. Access tokens go through the factory's default credentials; commands
+// that need no token run without one when none is configured.
+func newClient() (apiclient.Client, error) {
+	tok, err := resolveToken(requiredToken)
+	if err != nil {
+		return nil, err
+	}
+	if tok.token != "" {
+		cliutils.Info().Str("token", tok.kind).Str("source", tok.source).Msg(tokenCommand + ": authenticating")
+	}
+	// The default credentials refuse to build a client without an access
+	// token, which public endpoints do not need.
+	if tok.kind == "root" || requiredToken == needNone && tok.token == "" {
+		return clients.NewClientWithToken(tok.token), nil
+	}
+	c, err := clients.NewClient()
+	if err != nil {
+		return nil, &authError{msg: err.Error()}
+	}
+	return c, nil
+}
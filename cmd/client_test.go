@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/serptech/serp-cli/apiclient"
+	"github.com/serptech/serp-go/api/client"
+	"github.com/serptech/serp-go/api/common"
+)
+
+// fakeFactory serves canned origins without any HTTP traffic.
+type fakeFactory struct {
+	err     error
+	tokens  []string
+	queries []common.Query
+}
+
+func (f *fakeFactory) NewClient() (apiclient.Client, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.NewClientWithToken("default"), nil
+}
+
+func (f *fakeFactory) NewClientWithToken(token string) apiclient.Client {
+	f.tokens = append(f.tokens, token)
+	return fakeClient{f}
+}
+
+// fakeClient implements only the services the tests call; the rest panic
+// through the nil embedded interface.
+type fakeClient struct{ f *fakeFactory }
+
+func (c fakeClient) Origins() apiclient.OriginsService   { return fakeOrigins{f: c.f} }
+func (c fakeClient) Profiles() apiclient.ProfilesService { return nil }
+func (c fakeClient) Entries() apiclient.EntriesService   { return nil }
+func (c fakeClient) Tokens() apiclient.TokensService     { return nil }
+func (c fakeClient) Users() apiclient.UsersService       { return nil }
+func (c fakeClient) Utility() apiclient.UtilityService   { return nil }
+
+type fakeOrigins struct {
+	apiclient.OriginsService
+	f *fakeFactory
+}
+
+func (o fakeOrigins) List(query common.Query) (*client.Object, error) {
+	o.f.queries = append(o.f.queries, query)
+	return &client.Object{
+		"count":   1,
+		"next":    nil,
+		"results": []interface{}{map[string]interface{}{"id": 7, "name": "fake"}},
+	}, nil
+}
+
+func TestClientFactoryInjection(t *testing.T) {
+	isolateEnv(t, nil)
+	factory := &fakeFactory{}
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), factory, []string{"origins", "list", "--format", "csv", "--fields", "id,name", "--limit", "5"}, &stdout, &stderr)
+	if code != ExitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if got, want := stdout.String(), "id,name\n7,fake\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	if len(factory.queries) != 1 || factory.queries[0]["limit"] != 5 {
+		t.Errorf("queries = %v, want one query with limit 5", factory.queries)
+	}
+}
+
+func TestClientFactoryError(t *testing.T) {
+	isolateEnv(t, nil)
+	factory := &fakeFactory{err: errors.New("no credentials")}
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), factory, []string{"origins", "list"}, &stdout, &stderr)
+	if code != ExitAuth {
+		t.Errorf("exit code %d, want %d", code, ExitAuth)
+	}
+	if got, want := stderr.String(), "Error: no credentials\n"; got != want {
+		t.Errorf("stderr = %q, want %q", got, want)
+	}
+}
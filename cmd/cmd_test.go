@@ -0,0 +1,562 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/serptech/serp-cli/apiclient"
+	"github.com/serptech/serp-cli/config"
+	"github.com/serptech/serp-cli/journal"
+	"github.com/serptech/serp-cli/mock"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata/golden")
+
+func TestMain(m *testing.M) {
+	installTransport()
+	os.Exit(m.Run())
+}
+
+// cliTest runs one command line against a fresh mock API and compares its
+// exit code, stdout and stderr with testdata/golden/<name>.golden.
+type cliTest struct {
+	name string
+	args []string
+	// env overrides the defaults set up by newHarness.
+	env map[string]string
+	// faults are injected into the mock API.
+	faults []mock.Fault
+	setup  func(t *testing.T, h *harness)
+	// interrupted runs the command with an already cancelled context, as if
+	// it had received SIGINT before its first request.
+	interrupted bool
+}
+
+type harness struct {
+	server *httptest.Server
+	dir    string
+}
+
+// serpEnv lists every variable the CLI reads or writes, so each test starts
+// from the same environment and leaves nothing behind.
+var serpEnv = []string{
+	"SERP_ACCESS_TOKEN", "SERP_ROOT_TOKEN", "SERP_BASE_URL",
+	"SERP_CONFIG", "SERP_CONTEXT", "SERP_DEBUG", "SERP_PASSPHRASE",
+}
+
+func newHarness(t *testing.T, env map[string]string, faults []mock.Fault) *harness {
+	t.Helper()
+
+	fixtures, err := mock.LoadFixtures(filepath.Join("testdata", "fixtures.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures.Faults = append(fixtures.Faults, faults...)
+	api, err := mock.NewServer(mock.Options{Fixtures: fixtures})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &harness{server: httptest.NewServer(api), dir: t.TempDir()}
+	t.Cleanup(h.server.Close)
+
+	defaults := map[string]string{
+		"SERP_ACCESS_TOKEN": mock.DefaultAccessToken,
+		"SERP_BASE_URL":     h.server.URL,
+		"SERP_CONFIG":       filepath.Join(h.dir, "config.yaml"),
+	}
+	for key, value := range env {
+		defaults[key] = value
+	}
+	isolateEnv(t, defaults)
+	return h
+}
+
+// isolateEnv sets every SERP_* variable to its value in env, or clears it, for
+// the duration of the test. Without an explicit SERP_CONFIG the config file
+// points into a temporary directory.
+func isolateEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, key := range serpEnv {
+		t.Setenv(key, env[key])
+	}
+	if env["SERP_CONFIG"] == "" {
+		t.Setenv("SERP_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+	}
+}
+
+// normalize replaces values that differ between runs with placeholders.
+func (h *harness) normalize(s string) string {
+	s = strings.ReplaceAll(s, h.server.URL, "{{SERVER}}")
+	s = strings.ReplaceAll(s, h.dir, "{{TMP}}")
+	return requestIDPattern.ReplaceAllString(s, `"request_id":"{{REQUEST_ID}}"`)
+}
+
+var requestIDPattern = regexp.MustCompile(`"request_id":"[^"]*"`)
+
+func (tc cliTest) run(t *testing.T) {
+	// Setups may change the working directory.
+	golden, err := filepath.Abs(filepath.Join("testdata", "golden", tc.name+".golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newHarness(t, tc.env, tc.faults)
+	if tc.setup != nil {
+		tc.setup(t, h)
+	}
+
+	args := make([]string, len(tc.args))
+	for i, arg := range tc.args {
+		args[i] = strings.ReplaceAll(arg, "{{TMP}}", h.dir)
+		args[i] = strings.ReplaceAll(args[i], "{{SERVER}}", h.server.URL)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if tc.interrupted {
+		cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run(ctx, apiclient.Default, args, &stdout, &stderr)
+
+	var got bytes.Buffer
+	fmt.Fprintf(&got, "$ serptech %s\n", strings.Join(tc.args, " "))
+	fmt.Fprintf(&got, "--- exit code ---\n%d\n", code)
+	fmt.Fprintf(&got, "--- stdout ---\n%s", h.normalize(stdout.String()))
+	fmt.Fprintf(&got, "--- stderr ---\n%s", h.normalize(stderr.String()))
+
+	if *update {
+		if err := os.WriteFile(golden, got.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run go test ./cmd -update to create it)", err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("output differs from %s\n--- got ---\n%s\n--- want ---\n%s", golden, got.String(), want)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	const (
+		known   = "testdata/photos/known.jpg"
+		unknown = "testdata/photos/new.jpg"
+	)
+	root := map[string]string{"SERP_ROOT_TOKEN": mock.DefaultRootToken}
+	withContexts := func(t *testing.T, h *harness) {
+		cfg, err := config.Load(filepath.Join(h.dir, "config.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.SetContext("prod", &config.Context{BaseURL: "https://api.example.com", AccessToken: "prod-access-token"})
+		cfg.SetContext("local", &config.Context{BaseURL: h.server.URL, AccessToken: mock.DefaultAccessToken})
+		if err := cfg.UseContext("local"); err != nil {
+			t.Fatal(err)
+		}
+		if err := cfg.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// rotatedInConfig runs with the "local" context as the only source of the
+	// access token and checks which token it holds afterwards.
+	rotatedInConfig := func(want string) func(t *testing.T, h *harness) {
+		return func(t *testing.T, h *harness) {
+			withContexts(t, h)
+			t.Setenv("SERP_ACCESS_TOKEN", "")
+			t.Setenv("SERP_BASE_URL", "")
+			t.Cleanup(func() {
+				cfg, err := config.Load(filepath.Join(h.dir, "config.yaml"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := cfg.Contexts["local"].AccessToken; got != want {
+					t.Errorf("context local has access token %q, want %q", got, want)
+				}
+			})
+		}
+	}
+
+	// recorded records origins list into traffic.har and then points the CLI
+	// at an address where nothing listens, so only a replay can succeed.
+	recorded := func(t *testing.T, h *harness) {
+		var out bytes.Buffer
+		args := []string{"origins", "list", "--format", "table", "--record", filepath.Join(h.dir, "traffic.har")}
+		if code := run(context.Background(), apiclient.Default, args, &out, &out); code != ExitOK {
+			t.Fatalf("recording failed with exit code %d: %s", code, out.String())
+		}
+		t.Setenv("SERP_BASE_URL", "http://127.0.0.1:1")
+	}
+
+	// withHelper stores the mock access token with the test credential helper,
+	// makes a "vault" context that uses it current and clears the token and
+	// base URL from the environment, so commands can only get them from there.
+	helperPath, err := filepath.Abs(filepath.Join("testdata", "credential-helper.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withHelper := func(t *testing.T, h *harness) {
+		t.Setenv("SERP_TEST_CREDENTIALS", filepath.Join(h.dir, "credentials"))
+		var out bytes.Buffer
+		args := []string{"config", "set-context", "vault", "--base-url", h.server.URL, "--token", mock.DefaultAccessToken, "--credential-helper", helperPath}
+		if code := run(context.Background(), apiclient.Default, args, &out, &out); code != ExitOK {
+			t.Fatalf("set-context failed with exit code %d: %s", code, out.String())
+		}
+		t.Setenv("SERP_ACCESS_TOKEN", "")
+		t.Setenv("SERP_BASE_URL", "")
+	}
+
+	// withEnvFiles writes env files into the test directory, with {{SERVER}}
+	// standing for the mock API, and runs the command from there.
+	withEnvFiles := func(files map[string]string) func(t *testing.T, h *harness) {
+		return func(t *testing.T, h *harness) {
+			for name, content := range files {
+				content = strings.ReplaceAll(content, "{{SERVER}}", h.server.URL)
+				if err := os.WriteFile(filepath.Join(h.dir, name), []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Chdir(h.dir)
+		}
+	}
+
+	// loggedIn saves the mock access token with login and clears it from the
+	// environment, so commands can only find it in the credential file.
+	loggedIn := func(t *testing.T, h *harness) {
+		t.Setenv("SERP_PASSPHRASE", "correct horse")
+		var out bytes.Buffer
+		args := []string{"login", "--token", mock.DefaultAccessToken}
+		if code := run(context.Background(), apiclient.Default, args, &out, &out); code != ExitOK {
+			t.Fatalf("login failed with exit code %d: %s", code, out.String())
+		}
+		t.Setenv("SERP_ACCESS_TOKEN", "")
+		t.Setenv("SERP_BASE_URL", "")
+	}
+	tokenOnStdin := func(token string) func(t *testing.T, h *harness) {
+		return func(t *testing.T, h *harness) {
+			t.Setenv("SERP_PASSPHRASE", "correct horse")
+			stdin = strings.NewReader(token + "\n")
+			t.Cleanup(func() { stdin = os.Stdin })
+		}
+	}
+
+	tests := []cliTest{
+		{name: "unknown_command", args: []string{"bogus"}},
+		{name: "unknown_flag", args: []string{"origins", "list", "--bogus"}},
+		{name: "version", args: []string{"version"}},
+
+		{name: "api_get", args: []string{"api", "GET", "/v1/origins/", "-f", "limit=1"}},
+		{name: "api_post", args: []string{"api", "POST", "/v1/origins/", "-f", "name=gate", "-F", "min_facesize=40"}},
+		{name: "api_paginate", args: []string{"api", "GET", "/v1/origins/", "--paginate", "--limit", "2", "--format", "ndjson"}},
+		{name: "api_not_found", args: []string{"api", "GET", "/v1/origins/99/"}},
+		{name: "api_absolute_url", args: []string{"api", "GET", "{{SERVER}}/v1/origins/1/"}},
+		{name: "api_foreign_host", args: []string{"api", "GET", "https://example.com/v1/origins/"}},
+		{name: "api_default_base_url", args: []string{"api", "GET", "{{SERVER}}/v1/origins/"}, env: map[string]string{"SERP_BASE_URL": ""}},
+
+		{name: "config_set_context", args: []string{"config", "set-context", "staging", "--base-url", "https://staging.example.com", "--token", "staging-token"}},
+		{name: "config_get_contexts", args: []string{"config", "get-contexts", "--format", "json"}, setup: withContexts},
+		{name: "config_use_context", args: []string{"config", "use-context", "prod"}, setup: withContexts},
+		{name: "config_use_context_missing", args: []string{"config", "use-context", "nope"}, setup: withContexts},
+		{name: "config_delete_context", args: []string{"config", "delete-context", "prod"}, setup: withContexts},
+		{name: "config_current_context", args: []string{"origins", "get", "--id", "1"}, setup: withContexts, env: map[string]string{"SERP_ACCESS_TOKEN": "", "SERP_BASE_URL": ""}},
+
+		{name: "credential_helper_get", args: []string{"origins", "get", "--id", "1"}, setup: withHelper},
+		{name: "credential_helper_contexts", args: []string{"config", "get-contexts", "--format", "table", "--fields", "name,current,access_token"}, setup: withHelper},
+		{name: "credential_helper_env", args: []string{"env", "--format", "csv"}, setup: withHelper},
+		{name: "credential_helper_keeps_tokens", args: []string{"config", "set-context", "vault", "--root-token", mock.DefaultRootToken}, setup: func(t *testing.T, h *harness) {
+			withHelper(t, h)
+			t.Cleanup(func() {
+				data, err := os.ReadFile(filepath.Join(h.dir, "credentials"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, want := range []string{"access_token=" + mock.DefaultAccessToken, "root_token=" + mock.DefaultRootToken} {
+					if !strings.Contains(string(data), want) {
+						t.Errorf("credentials miss %s:\n%s", want, data)
+					}
+				}
+			})
+		}},
+		{name: "credential_helper_erased", args: []string{"config", "delete-context", "vault"}, setup: func(t *testing.T, h *harness) {
+			withHelper(t, h)
+			t.Cleanup(func() {
+				if _, err := os.Stat(filepath.Join(h.dir, "credentials")); !os.IsNotExist(err) {
+					t.Errorf("credentials were not erased: %v", err)
+				}
+			})
+		}},
+		{name: "credential_helper_failure", args: []string{"origins", "get", "--id", "1"}, env: map[string]string{"SERP_ACCESS_TOKEN": "", "SERP_BASE_URL": ""}, setup: func(t *testing.T, h *harness) {
+			cfg, err := config.Load(filepath.Join(h.dir, "config.yaml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.SetContext("vault", &config.Context{BaseURL: h.server.URL, CredentialHelper: "!echo vault is sealed >&2; exit 1; :"})
+			if err := cfg.UseContext("vault"); err != nil {
+				t.Fatal(err)
+			}
+			if err := cfg.Save(); err != nil {
+				t.Fatal(err)
+			}
+		}},
+
+		{name: "env_file_default", args: []string{"env", "--format", "csv"}, setup: withEnvFiles(map[string]string{
+			".env": "# ignored: the environment has an access token\nSERP_ACCESS_TOKEN=dotenv-access-token\nSERP_ROOT_TOKEN=" + mock.DefaultRootToken + "\n",
+		})},
+		{name: "env_file_used", args: []string{"origins", "get", "--id", "1"}, env: map[string]string{"SERP_ACCESS_TOKEN": "", "SERP_BASE_URL": ""}, setup: withEnvFiles(map[string]string{
+			".env": "export SERP_BASE_URL={{SERVER}}\nSERP_ACCESS_TOKEN=\"" + mock.DefaultAccessToken + "\"\n",
+		})},
+		{name: "env_file_flags", args: []string{"--env-file", "first.env", "--env-file", "second.env", "--token", "flag-access-token", "env", "--format", "csv"}, setup: withEnvFiles(map[string]string{
+			".env":       "SERP_CONTEXT=not-loaded\n",
+			"first.env":  "SERP_ACCESS_TOKEN=first-access-token\nSERP_ROOT_TOKEN=first-root-token\n",
+			"second.env": "SERP_ROOT_TOKEN=second-root-token\n",
+		})},
+		{name: "env_file_below_context", args: []string{"origins", "get", "--id", "1"}, env: map[string]string{"SERP_ACCESS_TOKEN": "", "SERP_BASE_URL": ""}, setup: func(t *testing.T, h *harness) {
+			withContexts(t, h)
+			withEnvFiles(map[string]string{".env": "SERP_BASE_URL=http://127.0.0.1:1\n"})(t, h)
+		}},
+		{name: "env_file_below_login", args: []string{"origins", "get", "--id", "1"}, setup: func(t *testing.T, h *harness) {
+			loggedIn(t, h)
+			withEnvFiles(map[string]string{".env": "SERP_BASE_URL=http://127.0.0.1:1\n"})(t, h)
+		}},
+		{name: "env_file_missing", args: []string{"--env-file", "missing.env", "env"}, setup: withEnvFiles(nil)},
+		{name: "env_file_invalid", args: []string{"env"}, setup: withEnvFiles(map[string]string{".env": "SERP_ACCESS_TOKEN\n"})},
+
+		{name: "root_fallback_refused", args: []string{"origins", "get", "--id", "1"}, env: map[string]string{"SERP_ACCESS_TOKEN": "", "SERP_ROOT_TOKEN": mock.DefaultRootToken}},
+		{name: "root_fallback_allowed", args: []string{"--allow-root-fallback", "--log-level", "error", "origins", "get", "--id", "1"}, env: map[string]string{"SERP_ACCESS_TOKEN": "", "SERP_ROOT_TOKEN": mock.DefaultRootToken}},
+		{name: "root_fallback_access_only", args: []string{"--allow-root-fallback", "utility", "compare", "--photo1", known, "--photo2", known}, env: map[string]string{"SERP_ACCESS_TOKEN": "", "SERP_ROOT_TOKEN": mock.DefaultRootToken}},
+		{name: "no_token_public_endpoint", args: []string{"utility", "health"}, env: map[string]string{"SERP_ACCESS_TOKEN": ""}},
+		{name: "root_token_public_endpoint", args: []string{"utility", "health"}, env: map[string]string{"SERP_ACCESS_TOKEN": "", "SERP_ROOT_TOKEN": mock.DefaultRootToken}},
+		{name: "root_token_from_login", args: []string{"users", "get", "--id", "1"}, setup: func(t *testing.T, h *harness) {
+			loggedIn(t, h)
+			t.Setenv("SERP_BASE_URL", h.server.URL)
+			var out bytes.Buffer
+			args := []string{"login", "--root", "--root-token", mock.DefaultRootToken}
+			if code := run(context.Background(), apiclient.Default, args, &out, &out); code != ExitOK {
+				t.Fatalf("login failed with exit code %d: %s", code, out.String())
+			}
+			t.Setenv("SERP_BASE_URL", "")
+			t.Setenv("SERP_ROOT_TOKEN", "")
+		}},
+
+		{name: "login_with_token", args: []string{"login", "--with-token"}, setup: tokenOnStdin(mock.DefaultAccessToken)},
+		{name: "login_root", args: []string{"login", "--root", "--root-token", mock.DefaultRootToken}, setup: tokenOnStdin("")},
+		{name: "login_rejected", args: []string{"login", "--with-token"}, setup: tokenOnStdin("bogus-token")},
+		{name: "login_no_passphrase", args: []string{"login", "--token", mock.DefaultAccessToken}},
+		{name: "login_used", args: []string{"origins", "get", "--id", "1"}, setup: loggedIn},
+		{name: "login_env", args: []string{"env", "--format", "csv"}, setup: loggedIn},
+		{name: "login_wrong_passphrase", args: []string{"origins", "get", "--id", "1"}, setup: func(t *testing.T, h *harness) {
+			loggedIn(t, h)
+			t.Setenv("SERP_PASSPHRASE", "wrong")
+		}},
+		{name: "login_unused_no_passphrase", args: []string{"version"}, setup: func(t *testing.T, h *harness) {
+			loggedIn(t, h)
+			t.Setenv("SERP_PASSPHRASE", "")
+			t.Setenv("SERP_ACCESS_TOKEN", mock.DefaultAccessToken)
+			t.Setenv("SERP_BASE_URL", h.server.URL)
+		}},
+		{name: "login_env_no_passphrase", args: []string{"env", "--format", "csv", "--fields", "name,source"}, setup: func(t *testing.T, h *harness) {
+			loggedIn(t, h)
+			t.Setenv("SERP_PASSPHRASE", "")
+			t.Setenv("SERP_ACCESS_TOKEN", mock.DefaultAccessToken)
+		}},
+		{name: "auth_status", args: []string{"auth", "status", "--format", "table"}, setup: func(t *testing.T, h *harness) {
+			loggedIn(t, h)
+			t.Setenv("SERP_BASE_URL", h.server.URL)
+			t.Setenv("SERP_ROOT_TOKEN", mock.DefaultRootToken)
+		}},
+		{name: "auth_status_bad_token", args: []string{"auth", "status", "--format", "table"}, env: map[string]string{"SERP_ACCESS_TOKEN": "bogus-token"}},
+		{name: "logout", args: []string{"logout"}, setup: loggedIn},
+		{name: "logout_not_logged_in", args: []string{"logout"}},
+
+		{name: "origins_help", args: []string{"origins"}},
+		{name: "origins_list", args: []string{"origins", "list"}},
+		{name: "origins_list_table", args: []string{"origins", "list", "--format", "table"}},
+		{name: "origins_list_yaml", args: []string{"origins", "list", "--format", "yaml", "--limit", "1"}},
+		{name: "origins_list_csv", args: []string{"origins", "list", "--format", "csv", "--fields", "id,name"}},
+		{name: "origins_list_all", args: []string{"origins", "list", "--all", "--limit", "1", "--format", "ndjson"}},
+		{name: "origins_list_max_items", args: []string{"origins", "list", "--max-items", "2", "--format", "ndjson"}},
+		{name: "origins_list_search", args: []string{"origins", "list", "--search", "park", "--format", "table"}},
+		{name: "origins_list_query", args: []string{"origins", "list", "--query", "results[?is_active].name"}},
+		{name: "origins_list_all_template", args: []string{"origins", "list", "--all", "--limit", "1", "--template", "{{range .results}}{{.id}} {{.name}}\n{{end}}"}},
+		{name: "origins_list_all_query", args: []string{"origins", "list", "--all", "--limit", "1", "--query", "[].id"}},
+		{name: "origins_list_template", args: []string{"origins", "list", "--template", "{{range .results}}{{.id}} {{.name}}\n{{end}}"}},
+		{name: "origins_get", args: []string{"origins", "get", "--id", "1"}},
+		{name: "origins_get_missing_id", args: []string{"origins", "get"}},
+		{name: "origins_get_not_found", args: []string{"origins", "get", "--id", "99"}},
+		{name: "origins_get_not_found_json", args: []string{"origins", "get", "--id", "99", "--error-format", "json"}},
+		{name: "origins_create", args: []string{"origins", "create", "--name", "gate", "--min-facesize", "40"}},
+		{name: "origins_create_missing_name", args: []string{"origins", "create"}},
+		{name: "origins_update", args: []string{"origins", "update", "--id", "2", "--name", "garage", "--is-active=false"}},
+		{name: "origins_delete", args: []string{"origins", "delete", "--id", "3"}},
+		{name: "origins_no_token", args: []string{"origins", "list"}, env: map[string]string{"SERP_ACCESS_TOKEN": ""}},
+		{name: "origins_bad_token", args: []string{"origins", "list", "--token", "wrong"}},
+
+		{name: "profiles_create", args: []string{"profiles", "create", "--photo", unknown, "--origin-id", "1"}},
+		{name: "profiles_create_existing", args: []string{"profiles", "create", "--photo", known, "--origin-id", "2"}},
+		{name: "profiles_create_bad_origin", args: []string{"profiles", "create", "--photo", unknown, "--origin-id", "42"}},
+		{name: "profiles_create_missing_photo", args: []string{"profiles", "create", "--origin-id", "1"}},
+		{name: "profiles_search_match", args: []string{"profiles", "search", "--photo", known}},
+		{name: "profiles_search_no_match", args: []string{"profiles", "search", "--photo", unknown}},
+		{name: "profiles_delete", args: []string{"profiles", "delete", "--profile-id", "71fd00e9-573b-15a6-a5e0-4677bd258d22"}},
+		{name: "profiles_delete_not_found", args: []string{"profiles", "delete", "--profile-id", "missing"}},
+		{name: "profiles_reinit", args: []string{"profiles", "reinit", "--profile-id", "71fd00e9-573b-15a6-a5e0-4677bd258d22", "--photo", unknown}},
+		{name: "profiles_import", args: []string{"profiles", "import", "--dir", "testdata/photos/import", "--origin-id", "1", "--workers", "1", "--results", "{{TMP}}/results.csv"}},
+		{name: "profiles_import_limited", args: []string{"profiles", "import", "--dir", "testdata/photos/import", "--origin-id", "1", "--results", "{{TMP}}/results.csv", "--rate", "100/s", "--concurrency", "1"}},
+		{name: "profiles_import_manifest", args: []string{"profiles", "import", "--manifest", "testdata/photos/manifest.csv", "--workers", "1", "--results", "{{TMP}}/results.csv"}},
+		{name: "profiles_import_manifest_bad", args: []string{"profiles", "import", "--manifest", "{{TMP}}/manifest.csv"}, setup: func(t *testing.T, h *harness) {
+			if err := os.WriteFile(filepath.Join(h.dir, "manifest.csv"), []byte("photo,origin_id\nfirst.jpg,one\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{name: "profiles_import_resume", args: []string{"profiles", "import", "--dir", "testdata/photos/import", "--origin-id", "1", "--workers", "1", "--results", "{{TMP}}/results.csv", "--resume", "{{TMP}}/earlier.journal"}, setup: withImportJournal},
+		{name: "profiles_import_journal_exists", args: []string{"profiles", "import", "--dir", "testdata/photos/import", "--origin-id", "1", "--results", "{{TMP}}/results.csv", "--journal", "{{TMP}}/earlier.journal"}, setup: withImportJournal},
+		{name: "profiles_import_overwrite_journal", args: []string{"profiles", "import", "--dir", "testdata/photos/import", "--origin-id", "1", "--workers", "1", "--results", "{{TMP}}/results.csv", "--journal", "{{TMP}}/earlier.journal", "--overwrite-journal"}, setup: withImportJournal},
+		{name: "profiles_import_resume_other_job", args: []string{"profiles", "import", "--dir", "testdata/photos/import", "--origin-id", "1", "--results", "{{TMP}}/results.csv", "--resume", "{{TMP}}/other.journal"}, setup: func(t *testing.T, h *harness) {
+			jr, err := journal.Create(filepath.Join(h.dir, "other.journal"), "export")
+			if err != nil {
+				t.Fatal(err)
+			}
+			jr.Close()
+		}},
+		{name: "profiles_import_bad_rate", args: []string{"profiles", "import", "--dir", "testdata/photos/import", "--origin-id", "1", "--rate", "fast"}},
+		{name: "profiles_unknown_action", args: []string{"profiles", "enroll"}},
+
+		{name: "entries_list", args: []string{"entries", "list", "--format", "table"}},
+		{name: "entries_list_filtered", args: []string{"entries", "list", "--origin-ids", "2", "--conf", "2", "--format", "table"}},
+		{name: "entries_list_bad_date", args: []string{"entries", "list", "--date-from", "yesterday"}},
+		{name: "entries_delete", args: []string{"entries", "delete", "--id", "2"}},
+		{name: "entries_delete_missing_id", args: []string{"entries", "delete"}},
+		{name: "entries_stats_sources", args: []string{"entries", "stats", "sources"}},
+		{name: "entries_stats_sources_liveness", args: []string{"entries", "stats", "sources", "--liveness", "failed"}},
+
+		{name: "tokens_access_list", args: []string{"tokens", "access", "list", "--format", "table"}},
+		{name: "tokens_access_list_space", args: []string{"tokens", "access", "list", "--space-id", "2", "--format", "table"}},
+		{name: "tokens_access_create", args: []string{"tokens", "access", "create", "--permanent"}},
+		{name: "tokens_access_delete", args: []string{"tokens", "access", "delete", "--key", "spare-access-token"}},
+		{name: "tokens_access_rotate_config", args: []string{"tokens", "access", "rotate", "--key", mock.DefaultAccessToken}, setup: rotatedInConfig("mock-access-0001")},
+		{name: "tokens_access_rotate_login", args: []string{"tokens", "access", "rotate", "--key", mock.DefaultAccessToken}, setup: loggedIn},
+		{name: "tokens_access_rotate_environment", args: []string{"tokens", "access", "rotate", "--key", mock.DefaultAccessToken}},
+		{name: "tokens_access_rotate_other_key", args: []string{"tokens", "access", "rotate", "--key", "spare-access-token"}},
+		{name: "tokens_access_rotate_no_key", args: []string{"tokens", "access", "rotate"}},
+		{name: "tokens_access_rotate_unverified", args: []string{"tokens", "access", "rotate", "--key", mock.DefaultAccessToken}, setup: rotatedInConfig(mock.DefaultAccessToken),
+			faults: []mock.Fault{{Method: "GET", Path: "/v1/users/me/", Status: 401}}},
+		{name: "tokens_access_rotate_helper", args: []string{"tokens", "access", "rotate", "--key", mock.DefaultAccessToken}, env: map[string]string{"SERP_ACCESS_TOKEN": "", "SERP_BASE_URL": ""}, setup: func(t *testing.T, h *harness) {
+			withHelper(t, h)
+			t.Setenv("SERP_ACCESS_TOKEN", "")
+			store := filepath.Join(h.dir, "credentials")
+			creds := "access_token=" + mock.DefaultAccessToken + "\nroot_token=" + mock.DefaultRootToken + "\n"
+			if err := os.WriteFile(store, []byte(creds), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				got, err := os.ReadFile(store)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := "access_token=mock-access-0001\nroot_token=" + mock.DefaultRootToken + "\n"; string(got) != want {
+					t.Errorf("credential helper keeps %q, want %q", got, want)
+				}
+			})
+		}},
+		{name: "tokens_access_rotate_delete_failed", args: []string{"tokens", "access", "rotate", "--key", mock.DefaultAccessToken}, setup: rotatedInConfig("mock-access-0001"),
+			faults: []mock.Fault{{Method: "DELETE", Path: "/v1/tokens/access/" + mock.DefaultAccessToken + "/", Status: 403}}},
+		{name: "tokens_streams_list", args: []string{"tokens", "streams", "list"}},
+		{name: "tokens_streams_create", args: []string{"tokens", "streams", "create"}},
+		{name: "tokens_streams_delete_not_found", args: []string{"tokens", "streams", "delete", "--key", "missing"}},
+
+		{name: "users_me", args: []string{"users", "me"}},
+		{name: "users_list_tokens", args: []string{"users", "list-tokens", "--format", "table"}},
+		{name: "users_statistics", args: []string{"users", "statistics"}},
+		{name: "users_list", args: []string{"users", "list", "--format", "table"}, env: root},
+		{name: "users_list_search", args: []string{"users", "list", "--search", "oper", "--format", "table"}, env: root},
+		{name: "users_list_query_deprecated", args: []string{"users", "list", "--query", "oper", "--format", "table"}, env: root},
+		{name: "users_me_query", args: []string{"users", "me", "--query", "username"}},
+		{name: "users_list_search_query", args: []string{"users", "list", "--search", "o", "--query", "results[].username"}, env: root},
+		{name: "users_list_without_root", args: []string{"users", "list"}},
+		{name: "users_get", args: []string{"users", "get", "--id", "2"}, env: root},
+		{name: "users_update", args: []string{"users", "update", "--id", "2", "--username", "viewer", "--is-active=false"}, env: root},
+		{name: "users_update_missing_username", args: []string{"users", "update", "--id", "2", "--is-active=false"}, env: root},
+		{name: "users_patch", args: []string{"users", "patch", "--id", "2", "--is-active=false"}, env: root},
+
+		{name: "retry_transient", args: []string{"entries", "list", "--format", "table", "--retry-max-wait", "1ms"},
+			faults: []mock.Fault{{Path: "/v1/entries/", Times: 2, Status: 503}}},
+		{name: "retry_exhausted", args: []string{"entries", "list", "--retries", "1", "--retry-max-wait", "1ms"},
+			faults: []mock.Fault{{Path: "/v1/entries/", Status: 502}}},
+		{name: "retry_after_too_long", args: []string{"entries", "list", "--retry-max-wait", "1ms"},
+			faults: []mock.Fault{{Path: "/v1/entries/", Times: 1, Status: 429, RetryAfter: 60}}},
+		{name: "retry_skips_create", args: []string{"profiles", "create", "--photo", unknown, "--origin-id", "1", "--retry-max-wait", "1ms"},
+			faults: []mock.Fault{{Method: "POST", Path: "/v1/profiles/", Times: 1, Status: 503}}},
+		{name: "retry_create_opt_in", args: []string{"profiles", "create", "--photo", unknown, "--origin-id", "1", "--retry-max-wait", "1ms", "--retry-non-idempotent"},
+			faults: []mock.Fault{{Method: "POST", Path: "/v1/profiles/", Times: 1, Status: 503}}},
+		{name: "retry_search", args: []string{"profiles", "search", "--photo", known, "--retry-max-wait", "1ms"},
+			faults: []mock.Fault{{Path: "/v1/profiles/search/", Times: 1, Status: 500}}},
+
+		{name: "replay_recorded", args: []string{"replay", "{{TMP}}/traffic.har"}, setup: recorded},
+		{name: "replay_command", args: []string{"replay", "{{TMP}}/traffic.har", "--", "origins", "list", "--format", "csv", "--fields", "id,name"}, setup: recorded},
+		{name: "replay_unmatched", args: []string{"replay", "{{TMP}}/traffic.har", "--", "origins", "get", "--id", "1"}, setup: recorded},
+		{name: "replay_record_refused", args: []string{"replay", "{{TMP}}/traffic.har", "--record", "{{TMP}}/again.har"}, setup: recorded},
+		{name: "replay_missing_file", args: []string{"replay", "{{TMP}}/missing.har"}},
+
+		{name: "log_bad_level", args: []string{"origins", "list", "--log-level", "loud"}},
+		{name: "log_bad_format", args: []string{"origins", "list", "--log-format", "xml"}},
+		{name: "log_file_keeps_output_clean", args: []string{"origins", "list", "--format", "csv", "--fields", "id", "--debug", "--log-file", "{{TMP}}/cli.log"}},
+
+		{name: "timeout_exceeded", args: []string{"origins", "list", "--timeout", "20ms", "--retries", "0"},
+			faults: []mock.Fault{{Path: "/v1/origins/", Latency: 200 * time.Millisecond}}},
+		{name: "timeout_retried", args: []string{"origins", "list", "--format", "table", "--timeout", "20ms", "--retry-max-wait", "1ms"},
+			faults: []mock.Fault{{Path: "/v1/origins/", Times: 1, Latency: 200 * time.Millisecond}}},
+		{name: "timeout_negative", args: []string{"origins", "list", "--timeout", "-1s"}},
+		{name: "interrupted_list", args: []string{"origins", "list"}, interrupted: true},
+		{name: "interrupted_import", args: []string{"profiles", "import", "--dir", "testdata/photos/import", "--origin-id", "1", "--results", "{{TMP}}/results.csv"}, interrupted: true},
+		{name: "interrupted_json", args: []string{"origins", "list", "--error-format", "json"}, interrupted: true},
+
+		{name: "utility_health", args: []string{"utility", "health"}},
+		{name: "utility_metrics", args: []string{"utility", "metrics"}},
+		{name: "utility_asm", args: []string{"utility", "asm", "--photo", known}},
+		{name: "utility_liveness", args: []string{"utility", "liveness", "--photo1", known, "--photo2", unknown}},
+		{name: "utility_compare_same", args: []string{"utility", "compare", "--photo1", known, "--photo2", known}},
+		{name: "utility_compare_different", args: []string{"utility", "compare", "--photo1", known, "--photo2", unknown, "--conf", "exact"}},
+		{name: "utility_compare_missing_photo", args: []string{"utility", "compare", "--photo1", known}},
+
+		{name: "mock_serve_bad_fixtures", args: []string{"mock", "serve", "--fixtures", "testdata/missing.yaml"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, tc.run)
+	}
+}
+
+// withImportJournal leaves the journal of an earlier import that enrolled
+// first.jpg and crashed while recording second.jpg.
+func withImportJournal(t *testing.T, h *harness) {
+	path := filepath.Join(h.dir, "earlier.journal")
+	jr, err := journal.Create(path, importJob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = jr.Record(journal.Record{Key: "first.jpg", Status: journal.Done, Result: map[string]string{"profile_id": "earlier-profile", "conf": "HA"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jr.Close()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(`{"key":"second.jpg","sta`); err != nil {
+		t.Fatal(err)
+	}
+}
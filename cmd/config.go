@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/serptech/serp-cli/config"
+	"github.com/serptech/serp-cli/credential"
+	cliutils "github.com/serptech/serp-cli/utils"
+	"github.com/spf13/cobra"
+)
+
+type contextView struct {
+	Name             string `json:"name"`
+	Current          bool   `json:"current"`
+	BaseURL          string `json:"base_url,omitempty"`
+	AccessToken      string `json:"access_token,omitempty"`
+	RootToken        string `json:"root_token,omitempty"`
+	CredentialHelper string `json:"credential_helper,omitempty"`
+}
+
+var contextCredentialHelper string
+
+func newConfigUseContextCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "use-context NAME",
+		Short: "Set the current context",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadDefault()
+			if err != nil {
+				return err
+			}
+			if err := cfg.UseContext(args[0]); err != nil {
+				return &usageError{msg: err.Error()}
+			}
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("save config: %w", err)
+			}
+			fmt.Fprintf(stdout, "switched to context %q\n", args[0])
+			return nil
+		},
+	}
+}
+
+func newConfigGetContextsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:         "get-contexts",
+		Short:       "List configured contexts",
+		Annotations: map[string]string{"resource": "contexts"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadDefault()
+			if err != nil {
+				return err
+			}
+
+			views := make([]contextView, 0, len(cfg.Contexts))
+			for _, name := range cfg.Names() {
+				ctx := cfg.Contexts[name]
+				views = append(views, contextView{
+					Name:             name,
+					Current:          name == cfg.CurrentContext,
+					BaseURL:          ctx.BaseURL,
+					AccessToken:      maskSecret(ctx.AccessToken),
+					RootToken:        maskSecret(ctx.RootToken),
+					CredentialHelper: ctx.CredentialHelper,
+				})
+			}
+			return writeOutput(views)
+		},
+	}
+}
+
+func newConfigSetContextCmd() *cobra.Command {
+	setContextCmd := &cobra.Command{
+		Use:   "set-context NAME",
+		Short: "Create or update a context from --base-url, --token and --root-token",
+		Long: `Creates or updates a context from --base-url, --token and --root-token.
+
+With --credential-helper the tokens are handed to that program to store and
+fetched from it whenever the context is used, instead of being written to the
+config file. The helper speaks git's credential helper protocol; see the
+README for details. --credential-helper "" removes the helper.`,
+		Args: usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := strings.TrimSpace(args[0])
+			cfg, err := config.LoadDefault()
+			if err != nil {
+				return err
+			}
+
+			ctx, ok := cfg.Contexts[name]
+			if !ok {
+				ctx = &config.Context{}
+			}
+			if cmd.Flags().Changed("base-url") {
+				ctx.BaseURL = strings.TrimSpace(baseURL)
+			}
+			if cmd.Flags().Changed("token") {
+				ctx.AccessToken = strings.TrimSpace(flagAccessToken)
+			}
+			if cmd.Flags().Changed("root-token") {
+				ctx.RootToken = strings.TrimSpace(flagRootToken)
+			}
+			if cmd.Flags().Changed("credential-helper") {
+				ctx.CredentialHelper = strings.TrimSpace(contextCredentialHelper)
+			}
+			if ctx.CredentialHelper != "" && (ctx.AccessToken != "" || ctx.RootToken != "") {
+				helper := credential.Helper{Command: ctx.CredentialHelper}
+				req := credentialRequest(name, ctx)
+				creds := credential.Credentials{AccessToken: ctx.AccessToken, RootToken: ctx.RootToken}
+				// The helper replaces everything it keeps for the context, so
+				// the token this invocation leaves alone has to be stored again.
+				if creds.AccessToken == "" || creds.RootToken == "" {
+					current, err := helper.Get(cmd.Context(), req)
+					if err != nil {
+						return err
+					}
+					if !cmd.Flags().Changed("token") && creds.AccessToken == "" {
+						creds.AccessToken = current.AccessToken
+					}
+					if !cmd.Flags().Changed("root-token") && creds.RootToken == "" {
+						creds.RootToken = current.RootToken
+					}
+				}
+				if err := helper.Store(cmd.Context(), req, creds); err != nil {
+					return err
+				}
+				ctx.AccessToken, ctx.RootToken = "", ""
+			}
+			if err := cfg.SetContext(name, ctx); err != nil {
+				return &usageError{msg: err.Error()}
+			}
+			if cfg.CurrentContext == "" {
+				cfg.CurrentContext = name
+			}
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("save config: %w", err)
+			}
+			fmt.Fprintf(stdout, "context %q saved to %s\n", name, cfg.Path())
+			return nil
+		},
+	}
+
+	setContextCmd.Flags().StringVar(&contextCredentialHelper, "credential-helper", "", "program that stores and supplies the tokens of this context")
+	return setContextCmd
+}
+
+func newConfigDeleteContextCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete-context NAME",
+		Short: "Delete a context",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadDefault()
+			if err != nil {
+				return err
+			}
+			if ctx, err := cfg.Context(args[0]); err == nil && ctx.CredentialHelper != "" {
+				helper := credential.Helper{Command: ctx.CredentialHelper}
+				if err := helper.Erase(cmd.Context(), credentialRequest(args[0], ctx)); err != nil {
+					return err
+				}
+			}
+			if err := cfg.DeleteContext(args[0]); err != nil {
+				return &usageError{msg: err.Error()}
+			}
+			if err := cfg.Save(); err != nil {
+				return fmt.Errorf("save config: %w", err)
+			}
+			fmt.Fprintf(stdout, "context %q successfully deleted\n", args[0])
+			return nil
+		},
+	}
+}
+
+func newConfigCmd() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:         "config",
+		Short:       "Manage named connection contexts",
+		Long:        "Contexts bundle a base URL, an access token and a root token under a name, stored in the serptech config file.",
+		Annotations: map[string]string{"credentials": "manual"},
+		Example: `  serptech config set-context staging --base-url https://staging.serptech.ru --token $TOKEN
+  serptech config use-context staging
+  serptech config get-contexts
+  serptech --context production origins list
+  serptech config delete-context staging`,
+	}
+
+	configUseContextCmd := newConfigUseContextCmd()
+	configGetContextsCmd := newConfigGetContextsCmd()
+	configSetContextCmd := newConfigSetContextCmd()
+	configDeleteContextCmd := newConfigDeleteContextCmd()
+
+	configCmd.AddCommand(configUseContextCmd, configGetContextsCmd, configSetContextCmd, configDeleteContextCmd)
+	return configCmd
+}
+
+// applyContext exports the selected context into the SERP_* environment used
+// by the client constructors. A context picked explicitly with --context
+// overrides the environment; the current context from the config file only
+// fills in what the environment leaves unset. The context's credential
+// helper and the tokens saved with serptech login are only consulted once
+// the command asks for a token that is still missing, see lookupTokens.
+// Commands that manage credentials themselves are left alone.
+func applyContext(cmd *cobra.Command) error {
+	if !usesContext(cmd) {
+		return nil
+	}
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+
+	name, explicit := selectedContext(cfg)
+	lookup := &tokenLookup{ctx: cmd.Context(), cfg: cfg, name: name}
+	if name != "" {
+		ctx, err := cfg.Context(name)
+		switch {
+		case err == nil:
+			if err := exportContext(ctx, explicit); err != nil {
+				return err
+			}
+			lookup.context = ctx
+		case explicit:
+			return &usageError{msg: err.Error()}
+		default:
+			cliutils.Warn().Msgf("current context %q is missing from %s", name, cfg.Path())
+		}
+	}
+	pendingLookup = lookup
+	return nil
+}
+
+// selectedContext returns the context chosen with --context, SERP_CONTEXT or
+// the config file, and whether it was chosen explicitly.
+func selectedContext(cfg *config.Config) (string, bool) {
+	if name := strings.TrimSpace(contextName); name != "" {
+		return name, true
+	}
+	if name := strings.TrimSpace(os.Getenv("SERP_CONTEXT")); name != "" {
+		return name, true
+	}
+	return cfg.CurrentContext, false
+}
+
+// usesContext reports whether cmd takes its connection settings from the
+// selected context. Commands annotated with credentials=manual opt out.
+func usesContext(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations["credentials"] == "manual" {
+			return false
+		}
+	}
+	return true
+}
+
+func exportContext(ctx *config.Context, explicit bool) error {
+	values := []struct{ key, value string }{
+		{"SERP_BASE_URL", ctx.BaseURL},
+		{"SERP_ACCESS_TOKEN", ctx.AccessToken},
+		{"SERP_ROOT_TOKEN", ctx.RootToken},
+	}
+	for _, v := range values {
+		if v.value == "" {
+			continue
+		}
+		if !explicit && os.Getenv(v.key) != "" && !fromDotenv(v.key) {
+			continue
+		}
+		if err := setSetting(v.key, v.value, "config"); err != nil {
+			return err
+		}
+	}
+	if fromDotenv("SERP_BASE_URL") && ctx.BaseURL == "" {
+		// The context's tokens are meant for the default base URL.
+		os.Unsetenv("SERP_BASE_URL")
+		delete(settingSources, "SERP_BASE_URL")
+	}
+	return nil
+}
+
+// exportTokens sets the tokens that are needed and known, found in source.
+func exportTokens(source string, needAccess bool, access string, needRoot bool, root string) error {
+	if needAccess && access != "" {
+		if err := setSetting("SERP_ACCESS_TOKEN", access, source); err != nil {
+			return err
+		}
+	}
+	if needRoot && root != "" {
+		if err := setSetting("SERP_ROOT_TOKEN", root, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// credentialRequest describes the named context to its credential helper.
+func credentialRequest(name string, ctx *config.Context) credential.Request {
+	return credential.Request{Context: name, BaseURL: ctx.BaseURL}
+}
+
+func maskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 8 {
+		return "****"
+	}
+	return value[:4] + "****" + value[len(value)-4:]
+}
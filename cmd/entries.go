@@ -2,11 +2,9 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
-	"github.com/serptech/serp-go/api/client"
 	"github.com/serptech/serp-go/api/common"
 	"github.com/serptech/serp-go/api/entries"
 	"github.com/spf13/cobra"
@@ -31,121 +29,169 @@ var (
 	entriesStatsDateTo        string
 )
 
-var entriesCmd = &cobra.Command{
-	Use:   "entries",
-	Short: "Inspect recognition entries and statistics",
-}
+func newEntriesListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List recognition entries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			filters := map[string]interface{}{}
+			if cmd.Flag("origin-ids").Changed {
+				filters["origin_ids"] = strings.TrimSpace(entriesListOriginIDs)
+			}
+			if cmd.Flag("spaces-ids").Changed {
+				filters["spaces_ids"] = strings.TrimSpace(entriesListSpaceIDs)
+			}
+			if cmd.Flag("person-ids").Changed {
+				filters["person_ids"] = strings.TrimSpace(entriesListPersonIDs)
+			}
+			if cmd.Flag("conf").Changed {
+				filters["conf"] = strings.TrimSpace(entriesListConf)
+			}
+			if cmd.Flag("date-from").Changed {
+				trimmed := strings.TrimSpace(entriesListDateFrom)
+				if trimmed != "" {
+					parsed, err := parseDate(trimmed)
+					if err != nil {
+						return err
+					}
+					filters["date_from"] = parsed.Format(time.RFC3339)
+				}
+			}
+			if cmd.Flag("date-to").Changed {
+				trimmed := strings.TrimSpace(entriesListDateTo)
+				if trimmed != "" {
+					parsed, err := parseDate(trimmed)
+					if err != nil {
+						return err
+					}
+					filters["date_to"] = parsed.Format(time.RFC3339)
+				}
+			}
 
-var entriesListCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List recognition entries",
-	Run: func(cmd *cobra.Command, args []string) {
-		c := resolveEntriesClient(false)
-
-		query := common.NewPaginationQuery(limit, offset)
-		if cmd.Flag("origin-ids").Changed {
-			query["origin_ids"] = strings.TrimSpace(entriesListOriginIDs)
-		}
-		if cmd.Flag("spaces-ids").Changed {
-			query["spaces_ids"] = strings.TrimSpace(entriesListSpaceIDs)
-		}
-		if cmd.Flag("person-ids").Changed {
-			query["person_ids"] = strings.TrimSpace(entriesListPersonIDs)
-		}
-		if cmd.Flag("conf").Changed {
-			query["conf"] = strings.TrimSpace(entriesListConf)
-		}
-		if cmd.Flag("date-from").Changed {
-			trimmed := strings.TrimSpace(entriesListDateFrom)
-			if trimmed != "" {
-				parsed, err := parseDate(trimmed)
-				ifErrorExit(err)
-				query["date_from"] = parsed.Format(time.RFC3339)
-			}
-		}
-		if cmd.Flag("date-to").Changed {
-			trimmed := strings.TrimSpace(entriesListDateTo)
-			if trimmed != "" {
-				parsed, err := parseDate(trimmed)
-				ifErrorExit(err)
-				query["date_to"] = parsed.Format(time.RFC3339)
-			}
-		}
-
-		resp, err := c.Entries().List(query)
-		ifErrorExit(err)
-		writeOutput(resp)
-	},
+			return writeList(func(pageLimit, pageOffset int) (interface{}, error) {
+				query := common.NewPaginationQuery(pageLimit, pageOffset)
+				for key, value := range filters {
+					query[key] = value
+				}
+				resp, err := c.Entries().List(query)
+				if err != nil {
+					return nil, fmt.Errorf("list entries: %w", err)
+				}
+				return resp, nil
+			})
+		},
+	}
 }
 
-var entriesDeleteCmd = &cobra.Command{
-	Use:   "delete",
-	Short: "Delete entry by identifier",
-	Run: func(cmd *cobra.Command, args []string) {
-		if entriesDeleteID == 0 {
-			printAndExit("entry id is required")
-		}
-		c := resolveEntriesClient(false)
-		ifErrorExit(c.Entries().Delete(entriesDeleteID))
-		fmt.Printf("entry %d successfully deleted\n", entriesDeleteID)
-	},
+func newEntriesDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete",
+		Short: "Delete entry by identifier",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if entriesDeleteID == 0 {
+				return usageErrorf("entry id is required")
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			if err := c.Entries().Delete(entriesDeleteID); err != nil {
+				return fmt.Errorf("delete entry %d: %w", entriesDeleteID, err)
+			}
+			fmt.Fprintf(stdout, "entry %d successfully deleted\n", entriesDeleteID)
+			return nil
+		},
+	}
 }
 
-var entriesStatsCmd = &cobra.Command{
-	Use:   "stats",
-	Short: "Retrieve entry statistics",
+func newEntriesStatsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "Retrieve entry statistics",
+	}
 }
 
-var entriesStatsSourcesCmd = &cobra.Command{
-	Use:   "sources",
-	Short: "Show statistics grouped by origins",
-	Run: func(cmd *cobra.Command, args []string) {
-		var req entries.StatsSourcesRequest
-		c := resolveEntriesClient(false)
-
-		if cmd.Flag("person-ids").Changed {
-			req.PersonIDs = strings.TrimSpace(entriesStatsPersonIDs)
-		}
-		if cmd.Flag("conf").Changed {
-			parsedConf, err := resolveConf(entriesStatsConfValue)
-			ifErrorExit(err)
-			req.Conf = parsedConf
-		}
-		if cmd.Flag("liveness").Changed {
-			parsedLiveness, err := resolveLiveness(entriesStatsLivenessValue)
-			ifErrorExit(err)
-			req.Liveness = parsedLiveness
-		}
-		if cmd.Flag("source-id").Changed {
-			req.Source = entriesStatsSourceID
-		}
-		if cmd.Flag("entry-id-from").Changed {
-			req.EntryIdFrom = entriesStatsEntryIDFrom
-		}
-		if cmd.Flag("date-from").Changed {
-			trimmed := strings.TrimSpace(entriesStatsDateFrom)
-			if trimmed != "" {
-				parsed, err := parseDate(trimmed)
-				ifErrorExit(err)
-				req.DateFrom = parsed
-			}
-		}
-		if cmd.Flag("date-to").Changed {
-			trimmed := strings.TrimSpace(entriesStatsDateTo)
-			if trimmed != "" {
-				parsed, err := parseDate(trimmed)
-				ifErrorExit(err)
-				req.DateTo = parsed
-			}
-		}
-
-		resp, err := c.Entries().StatsSources(req)
-		ifErrorExit(err)
-		writeOutput(resp)
-	},
+func newEntriesStatsSourcesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sources",
+		Short: "Show statistics grouped by origins",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var req entries.StatsSourcesRequest
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			if cmd.Flag("person-ids").Changed {
+				req.PersonIDs = strings.TrimSpace(entriesStatsPersonIDs)
+			}
+			if cmd.Flag("conf").Changed {
+				parsedConf, err := resolveConf(entriesStatsConfValue)
+				if err != nil {
+					return err
+				}
+				req.Conf = parsedConf
+			}
+			if cmd.Flag("liveness").Changed {
+				parsedLiveness, err := resolveLiveness(entriesStatsLivenessValue)
+				if err != nil {
+					return err
+				}
+				req.Liveness = parsedLiveness
+			}
+			if cmd.Flag("source-id").Changed {
+				req.Source = entriesStatsSourceID
+			}
+			if cmd.Flag("entry-id-from").Changed {
+				req.EntryIdFrom = entriesStatsEntryIDFrom
+			}
+			if cmd.Flag("date-from").Changed {
+				trimmed := strings.TrimSpace(entriesStatsDateFrom)
+				if trimmed != "" {
+					parsed, err := parseDate(trimmed)
+					if err != nil {
+						return err
+					}
+					req.DateFrom = parsed
+				}
+			}
+			if cmd.Flag("date-to").Changed {
+				trimmed := strings.TrimSpace(entriesStatsDateTo)
+				if trimmed != "" {
+					parsed, err := parseDate(trimmed)
+					if err != nil {
+						return err
+					}
+					req.DateTo = parsed
+				}
+			}
+
+			resp, err := c.Entries().StatsSources(req)
+			if err != nil {
+				return fmt.Errorf("entry statistics: %w", err)
+			}
+			return writeOutput(resp)
+		},
+	}
 }
 
-func init() {
+func newEntriesCmd() *cobra.Command {
+	entriesCmd := &cobra.Command{
+		Use:         "entries",
+		Short:       "Inspect recognition entries and statistics",
+		Annotations: map[string]string{"resource": "entries"},
+	}
+
+	entriesListCmd := newEntriesListCmd()
+	entriesDeleteCmd := newEntriesDeleteCmd()
+	entriesStatsCmd := newEntriesStatsCmd()
+	entriesStatsSourcesCmd := newEntriesStatsSourcesCmd()
+
 	entriesListCmd.Flags().StringVar(&entriesListOriginIDs, "origin-ids", "", "comma-separated list of origin identifiers")
 	entriesListCmd.Flags().StringVar(&entriesListSpaceIDs, "spaces-ids", "", "comma-separated list of space identifiers")
 	entriesListCmd.Flags().StringVar(&entriesListPersonIDs, "person-ids", "", "comma-separated list of person identifiers")
@@ -165,18 +211,5 @@ func init() {
 
 	entriesStatsCmd.AddCommand(entriesStatsSourcesCmd)
 	entriesCmd.AddCommand(entriesListCmd, entriesDeleteCmd, entriesStatsCmd)
-	rootCmd.AddCommand(entriesCmd)
-}
-
-func resolveEntriesClient(requireRoot bool) *client.Client {
-	if requireRoot {
-		rootToken := strings.TrimSpace(os.Getenv("SERP_ROOT_TOKEN"))
-		if rootToken == "" {
-			printAndExit("SERP_ROOT_TOKEN environment variable is required for this action")
-		}
-		return client.NewClientWithToken(rootToken)
-	}
-	c, err := client.NewClient()
-	ifErrorExit(err)
-	return c
+	return entriesCmd
 }
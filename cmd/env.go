@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/serptech/serp-cli/config"
+	"github.com/serptech/serp-cli/dotenv"
+	"github.com/serptech/serp-cli/redact"
+	cliutils "github.com/serptech/serp-cli/utils"
+	"github.com/serptech/serp-go/api/client"
+	"github.com/spf13/cobra"
+)
+
+// defaultEnvFile is read from the working directory when no --env-file is
+// given.
+const defaultEnvFile = ".env"
+
+var envFiles []string
+
+// settingSources records where the variables set by this invocation came
+// from. Variables missing from it were set by the caller's environment.
+var settingSources = map[string]string{}
+
+// loadedEnv lists the variables set from env files, which run unsets again.
+var loadedEnv []string
+
+// dotenvKeys are the variables set from the auto-discovered ./.env. They rank
+// below the selected context, see fromDotenv.
+var dotenvKeys = map[string]bool{}
+
+// envView is one row of serptech env.
+type envView struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+func newEnvCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "env",
+		Short: "Show the effective SERP_* settings and where each comes from",
+		Long: `Resolves the context, base URL and tokens the way other commands do and
+prints them with every SERP_* variable set, secrets masked. The source column
+tells where a value came from: flag, environment, the env file it was read
+from, config, credential helper, login or default.`,
+		Example: `  serptech env --format table
+  serptech --env-file staging.env env`,
+		Annotations: map[string]string{"resource": "env"},
+		Args:        usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadDefault()
+			if err != nil {
+				return err
+			}
+			// Look for the access token where a command would; the saved
+			// login is only unlocked when nothing else provides one.
+			if err := lookupTokens(needAccess); err != nil {
+				return err
+			}
+			return writeOutput(envViews(cfg))
+		},
+	}
+}
+
+// loadEnvFiles sets the variables of the --env-file files, or of ./.env when
+// none is given, that the environment leaves unset or empty. A later file
+// overrides an earlier one.
+//
+// ./.env may belong to whatever repository the command runs in, so only
+// SERP_* variables other than SERP_CONFIG are read from it; PATH, proxies or
+// another config file, with its credential helpers, need --env-file.
+func loadEnvFiles(cmd *cobra.Command) error {
+	paths, optional := envFiles, false
+	if !cmd.Flags().Changed("env-file") {
+		paths, optional = []string{defaultEnvFile}, true
+	}
+	for _, path := range paths {
+		vars, err := dotenv.Read(path, os.LookupEnv)
+		if optional && errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return usageErrorf("read env file: %v", err)
+		}
+		for _, v := range vars {
+			if optional && (!strings.HasPrefix(v.Key, "SERP_") || v.Key == "SERP_CONFIG") {
+				cliutils.Warn().Msgf("ignoring %s in %s: only SERP_* settings are read from it, use --env-file %s to load it", v.Key, path, path)
+				continue
+			}
+			if os.Getenv(v.Key) != "" && settingSources[v.Key] == "" {
+				continue
+			}
+			if err := setSetting(v.Key, v.Value, path); err != nil {
+				return err
+			}
+			loadedEnv = append(loadedEnv, v.Key)
+			if optional {
+				dotenvKeys[v.Key] = true
+			}
+		}
+	}
+	return nil
+}
+
+// unloadEnvFiles unsets the variables loadEnvFiles set and forgets where the
+// settings came from.
+func unloadEnvFiles() {
+	for _, key := range loadedEnv {
+		os.Unsetenv(key)
+	}
+	loadedEnv = nil
+	dotenvKeys = map[string]bool{}
+	settingSources = map[string]string{}
+}
+
+// fromDotenv reports whether key still holds the value of the
+// auto-discovered ./.env. Such values give way to the selected context and
+// saved logins, so that a .env cannot send their tokens to another base URL.
+func fromDotenv(key string) bool {
+	return dotenvKeys[key] && settingSources[key] == defaultEnvFile
+}
+
+// setSetting sets the environment variable key and records its source.
+func setSetting(key, value, source string) error {
+	if err := os.Setenv(key, value); err != nil {
+		return err
+	}
+	settingSources[key] = source
+	return nil
+}
+
+// settingSource tells where the environment variable key came from, or
+// returns "" when it is unset or empty.
+func settingSource(key string) string {
+	if source := settingSources[key]; source != "" {
+		return source
+	}
+	if os.Getenv(key) != "" {
+		return "environment"
+	}
+	return ""
+}
+
+func envViews(cfg *config.Config) []envView {
+	name, _ := selectedContext(cfg)
+	contextSource := "config"
+	switch {
+	case strings.TrimSpace(contextName) != "":
+		contextSource = "flag"
+	case strings.TrimSpace(os.Getenv("SERP_CONTEXT")) != "":
+		contextSource = settingSource("SERP_CONTEXT")
+	case name == "":
+		contextSource = ""
+	}
+	views := []envView{{Name: "context", Value: name, Source: contextSource}}
+
+	base := envView{Name: "SERP_BASE_URL", Value: os.Getenv("SERP_BASE_URL"), Source: settingSource("SERP_BASE_URL")}
+	if base.Value == "" {
+		base.Value, base.Source = client.DefaultBaseURL, "default"
+	}
+	views = append(views, base)
+
+	listed := map[string]bool{"SERP_BASE_URL": true, "SERP_CONTEXT": true}
+	var keys []string
+	for _, kv := range serpEnviron() {
+		key, value, _ := strings.Cut(kv, "=")
+		if !listed[key] && value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return envOrder(keys[i]) < envOrder(keys[j]) })
+	for _, key := range keys {
+		value := os.Getenv(key)
+		if redact.SensitiveName(key) {
+			value = maskSecret(value)
+		}
+		views = append(views, envView{Name: key, Value: value, Source: settingSource(key)})
+	}
+	return views
+}
+
+// envOrder lists the tokens right after the base URL and the rest by name.
+func envOrder(key string) string {
+	switch key {
+	case "SERP_ACCESS_TOKEN":
+		return "0"
+	case "SERP_ROOT_TOKEN":
+		return "1"
+	}
+	return "2" + key
+}
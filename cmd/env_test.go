@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/serptech/serp-cli/apiclient"
+)
+
+func TestDotenvOnlySetsSerpVariables(t *testing.T) {
+	isolateEnv(t, nil)
+	t.Setenv("HTTPS_PROXY", "")
+	config := os.Getenv("SERP_CONFIG")
+	dir := t.TempDir()
+	content := "HTTPS_PROXY=http://proxy.invalid\nSERP_CONFIG=repo.yaml\nSERP_ROOT_TOKEN=dotenv-root-token\n"
+	if err := os.WriteFile(filepath.Join(dir, defaultEnvFile), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+
+	defer unloadEnvFiles()
+	if err := loadEnvFiles(NewRootCmd(apiclient.Default)); err != nil {
+		t.Fatal(err)
+	}
+	if got := os.Getenv("HTTPS_PROXY"); got != "" {
+		t.Errorf("HTTPS_PROXY = %q, want it left alone", got)
+	}
+	if got := os.Getenv("SERP_CONFIG"); got != config {
+		t.Errorf("SERP_CONFIG = %q, want %q", got, config)
+	}
+	if got := os.Getenv("SERP_ROOT_TOKEN"); got != "dotenv-root-token" {
+		t.Errorf("SERP_ROOT_TOKEN = %q", got)
+	}
+	if !fromDotenv("SERP_ROOT_TOKEN") {
+		t.Error("SERP_ROOT_TOKEN is not marked as coming from .env")
+	}
+}
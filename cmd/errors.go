@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/serptech/serp-cli/transport"
+	"github.com/serptech/serp-go/api/client"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes returned by serptech. Scripts may branch on them, so existing
+// values must not change.
+const (
+	ExitOK             = 0   // success
+	ExitError          = 1   // unclassified failure
+	ExitUsage          = 2   // invalid arguments, flags or missing required values
+	ExitAuth           = 3   // missing, rejected or insufficient credentials
+	ExitNotFound       = 4   // the API reported that the resource does not exist
+	ExitAPI            = 5   // the API rejected the request or failed to process it
+	ExitNetwork        = 6   // the API could not be reached
+	ExitPartialFailure = 7   // a bulk command finished but some items failed
+	ExitInterrupted    = 130 // cancelled by SIGINT or SIGTERM
+)
+
+// errInterrupted reports a command cancelled by a signal.
+var errInterrupted = errors.New("interrupted")
+
+const (
+	errorFormatText = "text"
+	errorFormatJSON = "json"
+)
+
+// usageError reports a problem with how the command was invoked.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string { return e.msg }
+
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// authError reports missing credentials for the requested action.
+type authError struct {
+	msg string
+}
+
+func (e *authError) Error() string { return e.msg }
+
+func authErrorf(format string, args ...interface{}) error {
+	return &authError{msg: fmt.Sprintf(format, args...)}
+}
+
+// partialError reports a bulk command in which only some items failed.
+type partialError struct {
+	failed int
+	total  int
+}
+
+func (e *partialError) Error() string {
+	return fmt.Sprintf("%d of %d items failed", e.failed, e.total)
+}
+
+// reportedError carries the exit code of a nested command whose error has
+// already been printed.
+type reportedError struct {
+	code int
+}
+
+func (e *reportedError) Error() string {
+	return fmt.Sprintf("exit code %d", e.code)
+}
+
+// usageArgs marks positional argument validation failures as usage errors.
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return &usageError{msg: err.Error()}
+		}
+		return nil
+	}
+}
+
+func flagUsageError(cmd *cobra.Command, err error) error {
+	return &usageError{msg: err.Error()}
+}
+
+// exitCode maps an error returned by a command to one of the Exit* codes.
+// Errors from the API are classified by the HTTP status they carry.
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var usage *usageError
+	var auth *authError
+	var partial *partialError
+	switch {
+	case errors.Is(err, errInterrupted):
+		return ExitInterrupted
+	case errors.As(err, &usage):
+		return ExitUsage
+	case errors.As(err, &auth):
+		return ExitAuth
+	case errors.As(err, &partial):
+		return ExitPartialFailure
+	}
+
+	if failure := failureOf(err); failure != nil {
+		if failure.Err != nil {
+			return ExitNetwork
+		}
+		switch failure.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ExitAuth
+		case http.StatusNotFound:
+			return ExitNotFound
+		default:
+			return ExitAPI
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ExitNetwork
+	}
+	return ExitError
+}
+
+var exitKinds = map[int]string{
+	ExitError:          "error",
+	ExitUsage:          "usage",
+	ExitAuth:           "auth",
+	ExitNotFound:       "not_found",
+	ExitAPI:            "api",
+	ExitNetwork:        "network",
+	ExitPartialFailure: "partial_failure",
+	ExitInterrupted:    "interrupted",
+}
+
+// errorReport is the --error-format json representation of a failure.
+type errorReport struct {
+	Command    string `json:"command"`
+	Message    string `json:"message"`
+	Kind       string `json:"kind"`
+	ExitCode   int    `json:"exit_code"`
+	Status     int    `json:"status,omitempty"`
+	Code       string `json:"code,omitempty"`
+	APIMessage string `json:"api_message,omitempty"`
+	RequestID  string `json:"request_id,omitempty"`
+	Retryable  bool   `json:"retryable"`
+}
+
+func newErrorReport(cmd *cobra.Command, err error, code int) errorReport {
+	report := errorReport{
+		Message:  err.Error(),
+		Kind:     exitKinds[code],
+		ExitCode: code,
+	}
+	if cmd != nil {
+		report.Command = cmd.CommandPath()
+		if commandAction != "" {
+			report.Command += " " + commandAction
+		}
+	}
+
+	switch code {
+	case ExitUsage, ExitPartialFailure, ExitInterrupted:
+		return report
+	}
+	var auth *authError
+	if errors.As(err, &auth) {
+		return report
+	}
+
+	failure := failureOf(err)
+	if failure == nil {
+		var netErr net.Error
+		report.Retryable = errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+		return report
+	}
+	report.Status = failure.StatusCode
+	report.RequestID = failure.RequestID
+	report.Code, report.APIMessage = apiErrorDetails(failure.Body)
+	report.Retryable = failure.Err != nil || transport.RetryableStatus(failure.StatusCode)
+	return report
+}
+
+// apiErrorDetails pulls the error code and message out of an API error body.
+func apiErrorDetails(body []byte) (string, string) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", strings.TrimSpace(string(body))
+	}
+	pick := func(keys ...string) string {
+		for _, key := range keys {
+			if v, ok := payload[key]; ok && v != nil {
+				if s, ok := v.(string); ok {
+					return s
+				}
+				raw, _ := json.Marshal(v)
+				return string(raw)
+			}
+		}
+		return ""
+	}
+	return pick("code", "error_code"), pick("detail", "message", "error")
+}
+
+// printError reports err on w in the format chosen with --error-format.
+func printError(w io.Writer, cmd *cobra.Command, err error, code int) {
+	if errorFormat == errorFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		if enc.Encode(newErrorReport(cmd, err, code)) == nil {
+			return
+		}
+	}
+	fmt.Fprintf(w, "Error: %v\n", err)
+}
+
+// failureOf returns the failed HTTP exchange behind err, if any. The client
+// reports only the status and body of a failed response; the request ID is
+// taken from the exchange the transport observed when it is that response.
+func failureOf(err error) *transport.Exchange {
+	var exchangeErr *transport.ExchangeError
+	if errors.As(err, &exchangeErr) {
+		return exchangeErr.Exchange
+	}
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+	failure := &transport.Exchange{StatusCode: apiErr.StatusCode, Body: []byte(apiErr.Body)}
+	if observer != nil {
+		if last := observer.LastFailure(); last != nil && last.StatusCode == apiErr.StatusCode && strings.HasPrefix(apiErr.Body, string(last.Body)) {
+			failure = last
+		}
+	}
+	return failure
+}
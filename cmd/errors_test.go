@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/serptech/serp-cli/transport"
+	"github.com/serptech/serp-go/api/client"
+)
+
+func TestExitCodeFromError(t *testing.T) {
+	// Another worker's failure is the last one the transport saw; it must
+	// not decide how these errors are classified.
+	prev := observer
+	observer = transport.NewObserver(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 500, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+	}))
+	defer func() { observer = prev }()
+	req := httptest.NewRequest("GET", "http://api.test/v1/entries/", nil)
+	if _, err := observer.RoundTrip(req); err != nil || observer.LastFailure() == nil {
+		t.Fatalf("observer recorded no failure: %v", err)
+	}
+
+	network := &url.Error{Op: "Get", URL: "http://127.0.0.1:1/v1/origins/", Err: &transport.ExchangeError{
+		Exchange: &transport.Exchange{Err: errors.New("connection refused")},
+		Err:      errors.New("connection refused"),
+	}}
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", fmt.Errorf("get origin 9: %w", &client.APIError{StatusCode: 404, Body: `{"detail":"Not found."}`}), ExitNotFound},
+		{"forbidden", fmt.Errorf("list users: %w", &client.APIError{StatusCode: 403}), ExitAuth},
+		{"unauthorized", &client.APIError{StatusCode: 401}, ExitAuth},
+		{"server error", &client.APIError{StatusCode: 502}, ExitAPI},
+		{"api command", &transport.ExchangeError{Exchange: &transport.Exchange{StatusCode: 404}, Err: errors.New("GET /v1/x/: 404 Not Found")}, ExitNotFound},
+		{"network", fmt.Errorf("list origins: %w", network), ExitNetwork},
+		{"usage", usageErrorf("bad flag"), ExitUsage},
+		{"partial", &partialError{failed: 1, total: 2}, ExitPartialFailure},
+		{"plain", errors.New("boom"), ExitError},
+	}
+	for _, tc := range tests {
+		if got := exitCode(tc.err); got != tc.want {
+			t.Errorf("%s: exitCode = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestErrorReportFromError(t *testing.T) {
+	prev := observer
+	observer = nil
+	defer func() { observer = prev }()
+
+	err := fmt.Errorf("get origin 9: %w", &client.APIError{StatusCode: 503, Body: `{"code":"busy","detail":"try later"}`})
+	report := newErrorReport(nil, err, exitCode(err))
+	if report.Status != 503 || report.Code != "busy" || report.APIMessage != "try later" || !report.Retryable || report.Kind != "api" {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
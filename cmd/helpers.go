@@ -2,23 +2,101 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
+	"github.com/serptech/serp-cli/printer"
 	"github.com/serptech/serp-go/api/const/conf"
 	"github.com/serptech/serp-go/api/const/liveness"
-	"github.com/serptech/serp-go/utils"
+	"github.com/spf13/cobra"
 )
 
-func writeOutput(data interface{}) {
-	if outputPath != "" {
-		preOut, err := utils.GetPretty(data)
-		ifErrorExit(err)
-		ifErrorExit(utils.WriteToFile(outputPath, preOut))
-		return
+func writeOutput(data interface{}) error {
+	out, closeOut, err := openOutput()
+	if err != nil {
+		return err
 	}
-	ifErrorExit(utils.PrettyPrint(data))
+	if err := printer.New(printerOptions()).Print(out, data); err != nil {
+		closeOut()
+		return err
+	}
+	return closeOut()
+}
+
+// openOutput returns the destination for command results: the --output file
+// when set, stdout otherwise.
+func openOutput() (io.Writer, func() error, error) {
+	if outputPath == "" {
+		return stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+func printerOptions() printer.Options {
+	return printer.Options{
+		Format:   outputFormat,
+		Resource: outputResource,
+		Color:    outputPath == "" && stdout == io.Writer(os.Stdout),
+		Query:    outputQuery,
+		Fields:   printer.ParseFields(outputFields),
+		Template: outputTemplate,
+	}
+}
+
+// loadTemplate parses --template or --template-file, if given.
+func loadTemplate() (*template.Template, error) {
+	text := outputTemplateText
+	if outputTemplateFile != "" {
+		if text != "" {
+			return nil, usageErrorf("--template and --template-file are mutually exclusive")
+		}
+		data, err := os.ReadFile(outputTemplateFile)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	if text == "" {
+		return nil, nil
+	}
+	return printer.NewTemplate(text, template.FuncMap{"confName": confName})
+}
+
+// resourceOf returns the "resource" annotation of cmd or its nearest
+// ancestor, which selects default table and csv columns.
+func resourceOf(cmd *cobra.Command) string {
+	for c := cmd; c != nil; c = c.Parent() {
+		if resource, ok := c.Annotations["resource"]; ok {
+			return resource
+		}
+	}
+	return ""
+}
+
+// isEmptyResult reports whether an API response carries no data, such as an
+// empty list.
+func isEmptyResult(resp interface{}) (bool, error) {
+	normalized, err := printer.Normalize(resp)
+	if err != nil {
+		return false, err
+	}
+	switch v := normalized.(type) {
+	case nil:
+		return true, nil
+	case []interface{}:
+		return len(v) == 0, nil
+	case map[string]interface{}:
+		return len(v) == 0, nil
+	}
+	return false, nil
 }
 
 func stringPtr(v string) *string { return &v }
@@ -47,20 +125,45 @@ func resolveConf(value string) (conf.Conf, error) {
 	case "nf", "no-face":
 		return conf.Nf, nil
 	case "":
-		return 0, fmt.Errorf("conf value is required")
+		return 0, usageErrorf("conf value is required")
 	default:
 		val, err := strconv.Atoi(value)
 		if err != nil {
-			return 0, fmt.Errorf("unknown conf value %q", value)
+			return 0, usageErrorf("unknown conf value %q", value)
 		}
 		parsed := conf.Conf(val)
 		if err := parsed.Validate(); err != nil {
-			return 0, err
+			return 0, &usageError{msg: err.Error()}
 		}
 		return parsed, nil
 	}
 }
 
+var confNames = map[conf.Conf]string{
+	conf.Nm:     "nm",
+	conf.New:    "new",
+	conf.Exact:  "exact",
+	conf.Junk:   "junk",
+	conf.Ha:     "ha",
+	conf.Det:    "det",
+	conf.Reinit: "reinit",
+	conf.Nf:     "nf",
+}
+
+// confName is the reverse of resolveConf: it turns a numeric conf from an
+// API response into its short name, leaving unknown values as they are.
+func confName(value interface{}) string {
+	raw := strings.TrimSpace(fmt.Sprint(value))
+	val, err := strconv.Atoi(raw)
+	if err != nil {
+		return raw
+	}
+	if name, ok := confNames[conf.Conf(val)]; ok {
+		return name
+	}
+	return raw
+}
+
 func resolveLiveness(value string) (liveness.Liveness, error) {
 	lower := strings.ToLower(strings.TrimSpace(value))
 	switch lower {
@@ -71,9 +174,9 @@ func resolveLiveness(value string) (liveness.Liveness, error) {
 	case "undetermined":
 		return liveness.Undetermined, nil
 	case "":
-		return "", fmt.Errorf("liveness value is required")
+		return "", usageErrorf("liveness value is required")
 	default:
-		return "", fmt.Errorf("unknown liveness %q", value)
+		return "", usageErrorf("unknown liveness %q", value)
 	}
 }
 
@@ -88,5 +191,5 @@ func parseDate(value string) (time.Time, error) {
 			return parsed, nil
 		}
 	}
-	return time.Time{}, fmt.Errorf("unable to parse date %q", value)
+	return time.Time{}, usageErrorf("unable to parse date %q", value)
 }
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfName(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{0, "nm"},
+		{4, "ha"},
+		{"7", "nf"},
+		{" 2 ", "exact"},
+		{json.Number("3"), "junk"},
+		{42, "42"},
+		{-1, "-1"},
+		{"ha", "ha"},
+		{2.5, "2.5"},
+		{nil, "<nil>"},
+	}
+	for _, tc := range tests {
+		if got := confName(tc.in); got != tc.want {
+			t.Errorf("confName(%#v) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestFirstValue(t *testing.T) {
+	m := map[string]interface{}{"id": 1234567.0, "person_id": "p-1", "conf": nil, "name": "Ann"}
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{[]string{"id"}, "1234567"},
+		{[]string{"profile_id", "person_id"}, "p-1"},
+		{[]string{"conf", "name"}, "Ann"},
+		{[]string{"missing"}, ""},
+	}
+	for _, tc := range tests {
+		if got := firstValue(m, tc.keys...); got != tc.want {
+			t.Errorf("firstValue(%q) = %q, want %q", tc.keys, got, tc.want)
+		}
+	}
+}
+
+func TestLoadTemplate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "origins.tmpl")
+	if err := os.WriteFile(file, []byte(`{{range .results}}{{.id}}={{confName .conf}};{{end}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]interface{}{"results": []interface{}{
+		map[string]interface{}{"id": 1, "conf": 4},
+		map[string]interface{}{"id": 2, "conf": json.Number("1")},
+	}}
+
+	tests := []struct {
+		name, text, file string
+		want             string
+		usage            bool
+		fails            bool
+	}{
+		{name: "none"},
+		{name: "text", text: `{{range .results}}{{.id}},{{end}}`, want: "1,2,"},
+		{name: "file", file: file, want: "1=ha;2=new;"},
+		{name: "both", text: "{{.}}", file: file, usage: true},
+		{name: "missing file", file: filepath.Join(t.TempDir(), "missing.tmpl"), fails: true},
+		{name: "invalid", text: "{{range .results}}", fails: true},
+	}
+	for _, tc := range tests {
+		outputTemplateText, outputTemplateFile = tc.text, tc.file
+		tmpl, err := loadTemplate()
+		switch {
+		case tc.usage:
+			if _, ok := err.(*usageError); !ok {
+				t.Errorf("%s: error = %v, want a usage error", tc.name, err)
+			}
+			continue
+		case tc.fails:
+			if err == nil {
+				t.Errorf("%s: loadTemplate succeeded", tc.name)
+			}
+			continue
+		case err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if tc.want == "" {
+			if tmpl != nil {
+				t.Errorf("%s: loadTemplate = %v, want nil", tc.name, tmpl)
+			}
+			continue
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, data); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if out.String() != tc.want {
+			t.Errorf("%s: output = %q, want %q", tc.name, out.String(), tc.want)
+		}
+	}
+	outputTemplateText, outputTemplateFile = "", ""
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/serptech/serp-cli/apiclient"
+	"github.com/serptech/serp-cli/mock"
+	cliutils "github.com/serptech/serp-cli/utils"
+)
+
+func TestLogFile(t *testing.T) {
+	h := newHarness(t, nil, nil)
+	path := filepath.Join(h.dir, "cli.log")
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"origins", "list", "--format", "csv", "--fields", "id", "--log-level", "debug", "--log-format", "json", "--log-file", path}
+	if code := run(context.Background(), apiclient.Default, args, &stdout, &stderr); code != ExitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "id\n1\n2\n3\n" || stderr.Len() != 0 {
+		t.Errorf("logs leaked into the output:\nstdout: %s\nstderr: %s", stdout.String(), stderr.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), mock.DefaultAccessToken) {
+		t.Errorf("access token logged:\n%s", data)
+	}
+	var sawRequest bool
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		if event["command"] != "serptech origins" {
+			t.Errorf("log line %q has no command field", line)
+		}
+		if event["message"] == "http request" {
+			sawRequest = true
+			if id, _ := event["request_id"].(string); id == "" {
+				t.Errorf("request logged without request_id: %q", line)
+			}
+		}
+	}
+	if !sawRequest {
+		t.Errorf("no request in log:\n%s", data)
+	}
+}
+
+func TestLogLevelFromSerpDebug(t *testing.T) {
+	h := newHarness(t, nil, nil)
+	path := filepath.Join(h.dir, "cli.log")
+	t.Setenv("SERP_DEBUG", "true")
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"origins", "list", "--log-format", "json", "--log-file", path}
+	if code := run(context.Background(), apiclient.Default, args, &stdout, &stderr); code != ExitOK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"level":"debug"`) {
+		t.Errorf("SERP_DEBUG=true logged no debug lines:\n%s", data)
+	}
+}
+
+func TestCloseLogKeepsStartupOptions(t *testing.T) {
+	want := zerolog.WarnLevel
+	if level := cliutils.DefaultLogOptions().Level; level != "" {
+		var err error
+		if want, err = zerolog.ParseLevel(level); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cliutils.ConfigureLog(cliutils.LogOptions{Level: "trace"}); err != nil {
+		t.Fatal(err)
+	}
+	closeLog()
+	if got := zerolog.GlobalLevel(); got != want {
+		t.Errorf("level after closeLog = %s, want %s", got, want)
+	}
+}
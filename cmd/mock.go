@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/serptech/serp-cli/mock"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mockHost        string
+	mockPort        int
+	mockAccessToken string
+	mockRootToken   string
+	mockFixtures    string
+	mockFaults      string
+)
+
+func newMockServeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Serve an in-memory SERP API",
+		Long: `Serve an in-memory emulation of the SERP API endpoints used by this CLI.
+
+State lives in memory and is lost on exit. Recognition is deterministic:
+enrolling the same photo twice yields the same profile, searching for an
+enrolled photo returns an exact match and anything else is not matched.
+
+--fixtures seeds origins, profiles, users, tokens and entries from a YAML or
+JSON file, optionally generating synthetic entries from a fixed seed.
+--faults reads the "faults" section of another such file, so the same data
+can be served with different failure scenarios. See mock/examples for
+examples.`,
+		Example: `  serptech mock serve --port 8080
+  serptech mock serve --fixtures fixtures.yaml --faults flaky.yaml
+  serptech mock serve --mock-access-token dev-access-token --mock-root-token dev-root-token
+  SERP_BASE_URL=http://127.0.0.1:8080 SERP_ACCESS_TOKEN=mock-access-token serptech origins list`,
+		Args: usageArgs(cobra.NoArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fixtures, err := loadMockFixtures()
+			if err != nil {
+				return err
+			}
+			server, err := mock.NewServer(mock.Options{AccessToken: mockAccessToken, RootToken: mockRootToken, Fixtures: fixtures})
+			if err != nil {
+				return usageErrorf("%v", err)
+			}
+
+			addr := net.JoinHostPort(mockHost, strconv.Itoa(mockPort))
+			listener, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("listen on %s: %w", addr, err)
+			}
+
+			fmt.Fprintf(stderr, "mock SERP API listening on http://%s\n", listener.Addr())
+			fmt.Fprintf(stderr, "  SERP_BASE_URL=http://%s\n", listener.Addr())
+			fmt.Fprintf(stderr, "  SERP_ACCESS_TOKEN=%s\n", mockAccessToken)
+			fmt.Fprintf(stderr, "  SERP_ROOT_TOKEN=%s\n", mockRootToken)
+
+			// Execute turns SIGINT and SIGTERM into a cancelled context, so
+			// the server has to watch it to stop.
+			srv := &http.Server{Handler: server}
+			stop := context.AfterFunc(cmd.Context(), func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				srv.Shutdown(ctx)
+			})
+			defer stop()
+			if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			fmt.Fprintln(stderr, "mock SERP API stopped")
+			return nil
+		},
+	}
+}
+
+func newMockCmd() *cobra.Command {
+	mockCmd := &cobra.Command{
+		Use:         "mock",
+		Short:       "Run a local SERP API emulator for offline work",
+		Annotations: map[string]string{"credentials": "manual"},
+	}
+
+	mockServeCmd := newMockServeCmd()
+
+	mockServeCmd.Flags().StringVar(&mockHost, "host", "127.0.0.1", "interface to listen on")
+	mockServeCmd.Flags().IntVar(&mockPort, "port", 8080, "port to listen on")
+	mockServeCmd.Flags().StringVar(&mockAccessToken, "mock-access-token", mock.DefaultAccessToken, "access token accepted by the server")
+	mockServeCmd.Flags().StringVar(&mockRootToken, "mock-root-token", mock.DefaultRootToken, "root token accepted by the server")
+	mockServeCmd.Flags().StringVar(&mockFixtures, "fixtures", "", "YAML or JSON file with seed data")
+	mockServeCmd.Flags().StringVar(&mockFaults, "faults", "", "YAML or JSON file whose faults section configures fault injection")
+
+	mockCmd.AddCommand(mockServeCmd)
+	return mockCmd
+}
+
+func loadMockFixtures() (*mock.Fixtures, error) {
+	var fixtures *mock.Fixtures
+	if mockFixtures != "" {
+		loaded, err := mock.LoadFixtures(mockFixtures)
+		if err != nil {
+			return nil, usageErrorf("load fixtures: %v", err)
+		}
+		fixtures = loaded
+	}
+	if mockFaults != "" {
+		loaded, err := mock.LoadFixtures(mockFaults)
+		if err != nil {
+			return nil, usageErrorf("load faults: %v", err)
+		}
+		if fixtures == nil {
+			fixtures = &mock.Fixtures{}
+		}
+		fixtures.Faults = append(fixtures.Faults, loaded.Faults...)
+	}
+	return fixtures, nil
+}
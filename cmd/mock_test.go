@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/serptech/serp-cli/apiclient"
+)
+
+func TestMockServeStopsOnCancel(t *testing.T) {
+	isolateEnv(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	var stdout, stderr bytes.Buffer
+	done := make(chan int, 1)
+	go func() {
+		done <- run(ctx, apiclient.Default, []string{"mock", "serve", "--port", "0", "--mock-access-token", "custom-access-token", "--mock-root-token", "custom-root-token"}, &stdout, &stderr)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case code := <-done:
+		if code != ExitOK {
+			t.Errorf("exit code %d, want %d; stderr: %s", code, ExitOK, stderr.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("mock serve did not stop after its context was cancelled")
+	}
+	if !strings.Contains(stderr.String(), "SERP_ACCESS_TOKEN=custom-access-token") || !strings.Contains(stderr.String(), "SERP_ROOT_TOKEN=custom-root-token") || !strings.Contains(stderr.String(), "mock SERP API stopped") {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/serptech/serp-cli/printer"
+)
+
+// maxPageSize is the largest page the API accepts for list endpoints.
+const maxPageSize = 1000
+
+// pageFetcher requests a single page of a list endpoint.
+type pageFetcher func(pageLimit, pageOffset int) (interface{}, error)
+
+type listPage struct {
+	Count   *int            `json:"count"`
+	Next    json.RawMessage `json:"next"`
+	Results []interface{}   `json:"results"`
+}
+
+// writeList prints a list endpoint response. With --all it walks every page
+// starting at --offset and streams the merged items to the output as they arrive.
+func writeList(fetch pageFetcher) error {
+	if !fetchAll {
+		resp, err := fetch(limit, offset)
+		if err != nil {
+			return err
+		}
+		return writeOutput(resp)
+	}
+
+	w, err := newItemWriter()
+	if err != nil {
+		return err
+	}
+	err = walkPages(fetch, w.WriteItems)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func walkPages(fetch pageFetcher, sink func(items []interface{}) error) error {
+	pageSize := limit
+	if !limitSet || pageSize <= 0 || pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	pageOffset := offset
+	written := 0
+	for {
+		resp, err := fetch(pageSize, pageOffset)
+		if err != nil {
+			return err
+		}
+		page, err := decodePage(resp)
+		if err != nil {
+			return err
+		}
+
+		items := page.Results
+		if maxItems > 0 && written+len(items) > maxItems {
+			items = items[:maxItems-written]
+		}
+		if len(items) > 0 {
+			if err := sink(items); err != nil {
+				return err
+			}
+		}
+		written += len(items)
+		pageOffset += len(page.Results)
+
+		switch {
+		case len(page.Results) == 0:
+			return nil
+		case maxItems > 0 && written >= maxItems:
+			return nil
+		case page.Count != nil && pageOffset >= *page.Count:
+			return nil
+		case len(page.Next) > 0 && string(page.Next) == "null":
+			return nil
+		case len(page.Next) == 0 && len(page.Results) < pageSize:
+			return nil
+		}
+	}
+}
+
+func decodePage(resp interface{}) (*listPage, error) {
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var page listPage
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := unmarshalNumbers(trimmed, &page.Results); err != nil {
+			return nil, err
+		}
+		return &page, nil
+	}
+	if err := unmarshalNumbers(trimmed, &page); err != nil {
+		return nil, fmt.Errorf("unexpected list response: %w", err)
+	}
+	return &page, nil
+}
+
+func unmarshalNumbers(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// itemWriter streams list items to stdout or --output in the selected format.
+type itemWriter struct {
+	printer.ItemPrinter
+	closeOut func() error
+}
+
+func newItemWriter() (*itemWriter, error) {
+	out, closeOut, err := openOutput()
+	if err != nil {
+		return nil, err
+	}
+	return &itemWriter{
+		ItemPrinter: printer.NewItemPrinter(out, printerOptions()),
+		closeOut:    closeOut,
+	}, nil
+}
+
+func (w *itemWriter) Close() error {
+	if err := w.ItemPrinter.Close(); err != nil {
+		w.closeOut()
+		return err
+	}
+	return w.closeOut()
+}
@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/serptech/serp-go/api/client"
+	"github.com/serptech/serp-cli/apiclient"
 	"github.com/serptech/serp-go/api/common"
 	"github.com/serptech/serp-go/api/profiles"
 	"github.com/spf13/cobra"
@@ -15,6 +15,7 @@ const (
 	profileSearch = "search"
 	profileDelete = "delete"
 	profileReinit = "reinit"
+	profileImport = "import"
 )
 
 var (
@@ -28,43 +29,18 @@ var (
 	profileAllowJunk   bool
 )
 
-var profilesCmd = &cobra.Command{
-	Use:       "profiles [command]",
-	Short:     "Manage recognition profiles",
-	Long:      "Provides helpers for working with profile lifecycle using the SerpTech API.",
-	Example:   "serptech profiles create --photo img/profile.png --origin-id 42",
-	ValidArgs: []string{profileCreate, profileSearch, profileDelete, profileReinit},
-	Args:      cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		action := args[0]
-		c, err := client.NewClient()
-		ifErrorExit(err)
-
-		switch action {
-		case profileCreate:
-			handleProfileCreate(cmd, c)
-		case profileSearch:
-			handleProfileSearch(cmd, c)
-		case profileDelete:
-			handleProfileDelete(c)
-		case profileReinit:
-			handleProfileReinit(cmd, c)
-		default:
-			printAndExit(fmt.Sprintf("unsupported command %q", action))
-		}
-	},
-}
-
-func handleProfileCreate(cmd *cobra.Command, c *client.Client) {
+func handleProfileCreate(cmd *cobra.Command, c apiclient.Client) error {
 	if primaryPhotoPath == "" {
-		printAndExit("photo is required")
+		return usageErrorf("photo is required")
 	}
 	if profileOriginID == 0 {
-		printAndExit("origin-id is required")
+		return usageErrorf("origin-id is required")
 	}
 
 	photo, err := common.NewPhotoFromFile(primaryPhotoPath)
-	ifErrorExit(err)
+	if err != nil {
+		return fmt.Errorf("read photo: %w", err)
+	}
 
 	req := profiles.CreateRequest{
 		Photo:    photo,
@@ -82,49 +58,62 @@ func handleProfileCreate(cmd *cobra.Command, c *client.Client) {
 	}
 
 	resp, err := c.Profiles().Create(req)
-	ifErrorExit(err)
-	writeOutput(resp)
+	if err != nil {
+		return fmt.Errorf("create profile: %w", err)
+	}
+	return writeOutput(resp)
 }
 
-func handleProfileSearch(cmd *cobra.Command, c *client.Client) {
+func handleProfileSearch(cmd *cobra.Command, c apiclient.Client) error {
 	if primaryPhotoPath == "" {
-		printAndExit("photo is required")
+		return usageErrorf("photo is required")
 	}
 
 	primary, err := common.NewPhotoFromFile(primaryPhotoPath)
-	ifErrorExit(err)
+	if err != nil {
+		return fmt.Errorf("read photo: %w", err)
+	}
 
 	searchReq := profiles.SearchRequest{Photo: primary}
 	if secondaryPhotoPath != "" {
 		secondary, err := common.NewPhotoFromFile(secondaryPhotoPath)
-		ifErrorExit(err)
+		if err != nil {
+			return fmt.Errorf("read second photo: %w", err)
+		}
 		searchReq.SecondImageName = secondary.PhotoName
 		searchReq.SecondImageData = secondary.PhotoData
 	}
 
 	resp, err := c.Profiles().Search(searchReq)
-	ifErrorExit(err)
-	writeOutput(resp)
+	if err != nil {
+		return fmt.Errorf("search profiles: %w", err)
+	}
+	return writeOutput(resp)
 }
 
-func handleProfileDelete(c *client.Client) {
+func handleProfileDelete(c apiclient.Client) error {
 	if strings.TrimSpace(profileID) == "" {
-		printAndExit("profile-id is required")
+		return usageErrorf("profile-id is required")
+	}
+	if err := c.Profiles().Delete(profileID); err != nil {
+		return fmt.Errorf("delete profile %s: %w", profileID, err)
 	}
-	ifErrorExit(c.Profiles().Delete(profileID))
-	fmt.Printf("profile %s successfully deleted\n", profileID)
+	fmt.Fprintf(stdout, "profile %s successfully deleted\n", profileID)
+	return nil
 }
 
-func handleProfileReinit(cmd *cobra.Command, c *client.Client) {
+func handleProfileReinit(cmd *cobra.Command, c apiclient.Client) error {
 	if strings.TrimSpace(profileID) == "" {
-		printAndExit("profile-id is required")
+		return usageErrorf("profile-id is required")
 	}
 	if primaryPhotoPath == "" {
-		printAndExit("photo is required")
+		return usageErrorf("photo is required")
 	}
 
 	photo, err := common.NewPhotoFromFile(primaryPhotoPath)
-	ifErrorExit(err)
+	if err != nil {
+		return fmt.Errorf("read photo: %w", err)
+	}
 
 	req := profiles.ReinitRequest{Photo: photo}
 	if cmd.Flag("create-min-facesize").Changed {
@@ -135,15 +124,54 @@ func handleProfileReinit(cmd *cobra.Command, c *client.Client) {
 	}
 
 	resp, err := c.Profiles().Reinit(profileID, req)
-	ifErrorExit(err)
-	if len(resp) == 0 {
-		fmt.Println("reinit completed")
-		return
+	if err != nil {
+		return fmt.Errorf("reinit profile %s: %w", profileID, err)
+	}
+	if empty, err := isEmptyResult(resp); err != nil {
+		return err
+	} else if empty {
+		fmt.Fprintln(stdout, "reinit completed")
+		return nil
 	}
-	writeOutput(resp)
+	return writeOutput(resp)
 }
 
-func init() {
+func newProfilesCmd() *cobra.Command {
+	profilesCmd := &cobra.Command{
+		Use:         "profiles [command]",
+		Short:       "Manage recognition profiles",
+		Annotations: map[string]string{"resource": "profiles"},
+		Long:        "Provides helpers for working with profile lifecycle using the SerpTech API.",
+		Example: `  serptech profiles create --photo img/profile.png --origin-id 42
+  serptech profiles import --dir photos/ --origin-id 42 --workers 8
+  serptech profiles import --manifest people.csv --origin-id 42 --results enrolled.csv
+  serptech profiles import --manifest people.csv --origin-id 42 --resume enrolled.csv.journal`,
+		ValidArgs: []string{profileCreate, profileSearch, profileDelete, profileReinit, profileImport},
+		Args:      usageArgs(cobra.MinimumNArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			action := args[0]
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			switch action {
+			case profileCreate:
+				return handleProfileCreate(cmd, c)
+			case profileSearch:
+				return handleProfileSearch(cmd, c)
+			case profileDelete:
+				return handleProfileDelete(c)
+			case profileReinit:
+				return handleProfileReinit(cmd, c)
+			case profileImport:
+				return handleProfileImport(cmd, c)
+			default:
+				return usageErrorf("unsupported command %q", action)
+			}
+		},
+	}
+
 	profilesCmd.Flags().StringVarP(&primaryPhotoPath, "photo", "p", "", "path to primary photo for create/search/reinit")
 	profilesCmd.Flags().StringVar(&secondaryPhotoPath, "second-photo", "", "optional path to secondary photo for search")
 	profilesCmd.Flags().IntVar(&profileOriginID, "origin-id", 0, "origin identifier for profile create")
@@ -152,6 +180,13 @@ func init() {
 	profilesCmd.Flags().IntVar(&profileMinConf, "min-conf", 0, "minimum match confidence for reinit")
 	profilesCmd.Flags().BoolVar(&profileAllowHa, "create-ha", false, "allow creation when result confidence is HA")
 	profilesCmd.Flags().BoolVar(&profileAllowJunk, "create-junk", false, "allow creation when result confidence is junk")
-
-	rootCmd.AddCommand(profilesCmd)
+	profilesCmd.Flags().StringVar(&importDir, "dir", "", "directory of photos to import")
+	profilesCmd.Flags().StringVar(&importManifest, "manifest", "", "CSV manifest of photos to import (photo,origin_id,create_min_facesize,create_ha,create_junk)")
+	profilesCmd.Flags().StringVar(&importResultsPath, "results", "import-results.csv", "path to CSV file with import results")
+	profilesCmd.Flags().IntVar(&importWorkers, "workers", 4, "number of concurrent uploads during import")
+	profilesCmd.Flags().StringVar(&importJournalPath, "journal", "", "path to the import journal (default <results>.journal)")
+	profilesCmd.Flags().StringVar(&importResume, "resume", "", "resume an import from its journal, retrying only failed and unprocessed photos")
+	profilesCmd.Flags().BoolVar(&importOverwriteJournal, "overwrite-journal", false, "start a new import even if the journal of an earlier one exists")
+
+	return profilesCmd
 }
@@ -0,0 +1,403 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/serptech/serp-cli/apiclient"
+	"github.com/serptech/serp-cli/journal"
+	cliutils "github.com/serptech/serp-cli/utils"
+	"github.com/serptech/serp-go/api/common"
+	"github.com/serptech/serp-go/api/profiles"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importDir              string
+	importManifest         string
+	importResultsPath      string
+	importWorkers          int
+	importJournalPath      string
+	importResume           string
+	importOverwriteJournal bool
+)
+
+const importJob = "profiles-import"
+
+var importImageExts = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".bmp":  true,
+	".webp": true,
+}
+
+// importItem is a single photo to enroll together with its create options.
+type importItem struct {
+	File              string
+	Path              string
+	OriginID          int
+	CreateMinFacesize *int
+	CreateHa          *bool
+	CreateJunk        *bool
+}
+
+type importResult struct {
+	File      string
+	ProfileID string
+	Conf      string
+	Err       error
+	Skipped   bool
+	NotRun    bool
+}
+
+func handleProfileImport(cmd *cobra.Command, c apiclient.Client) error {
+	if (importDir == "") == (importManifest == "") {
+		return usageErrorf("exactly one of --dir or --manifest is required")
+	}
+	if importWorkers < 1 {
+		return usageErrorf("workers must be at least 1")
+	}
+
+	defaults := importItem{OriginID: profileOriginID}
+	if cmd.Flag("create-min-facesize").Changed {
+		defaults.CreateMinFacesize = intPtr(profileMinFacesize)
+	}
+	if cmd.Flag("create-ha").Changed {
+		defaults.CreateHa = boolPtr(profileAllowHa)
+	}
+	if cmd.Flag("create-junk").Changed {
+		defaults.CreateJunk = boolPtr(profileAllowJunk)
+	}
+
+	var (
+		items []importItem
+		err   error
+	)
+	if importDir != "" {
+		items, err = importItemsFromDir(importDir, defaults)
+	} else {
+		items, err = importItemsFromManifest(importManifest, defaults)
+	}
+	if err != nil {
+		return &usageError{msg: err.Error()}
+	}
+	if len(items) == 0 {
+		return usageErrorf("no photos found to import")
+	}
+	for _, item := range items {
+		if item.OriginID == 0 {
+			return usageErrorf("origin-id is required for %s", item.File)
+		}
+	}
+
+	jr, err := openImportJournal()
+	if err != nil {
+		return err
+	}
+
+	results := runProfileImport(cmd.Context(), c, items, importWorkers, jr)
+	if err := jr.Close(); err != nil {
+		return fmt.Errorf("close journal: %w", err)
+	}
+	if err := writeImportResults(importResultsPath, results); err != nil {
+		return fmt.Errorf("write import results: %w", err)
+	}
+
+	failed, skipped, notRun := 0, 0, 0
+	for _, r := range results {
+		switch {
+		case r.NotRun:
+			notRun++
+		case r.Err != nil:
+			failed++
+		case r.Skipped:
+			skipped++
+		}
+	}
+	fmt.Fprintf(stdout, "imported %d of %d photos (%d from earlier runs), %d failed; results written to %s\n",
+		len(results)-failed-notRun, len(results), skipped, failed, importResultsPath)
+	if notRun > 0 {
+		fmt.Fprintf(stdout, "interrupted before %d photos; continue with --resume %s\n", notRun, jr.Path())
+		return errInterrupted
+	}
+	if failed > 0 {
+		fmt.Fprintf(stdout, "retry the failed photos with --resume %s\n", jr.Path())
+		return &partialError{failed: failed, total: len(results)}
+	}
+	return nil
+}
+
+// openImportJournal resumes the journal given by --resume or starts a new
+// one at --journal, by default next to the results file. The journal of an
+// earlier import is only replaced with --overwrite-journal.
+func openImportJournal() (*journal.Journal, error) {
+	if importResume != "" {
+		return journal.Resume(importResume, importJob)
+	}
+	path := importJournalPath
+	if path == "" {
+		path = importResultsPath + ".journal"
+	}
+	if importOverwriteJournal {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	jr, err := journal.Create(path, importJob)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, usageErrorf("journal %s of an earlier import exists: continue it with --resume %s or start over with --overwrite-journal", path, path)
+	}
+	return jr, err
+}
+
+// runProfileImport uploads items with the given number of workers. Once ctx
+// is cancelled no further uploads start; the remaining items are marked as
+// not run and left out of the journal so that a resumed import picks them up.
+func runProfileImport(ctx context.Context, c apiclient.Client, items []importItem, workers int, jr *journal.Journal) []importResult {
+	results := make([]importResult, len(items))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = importJournaled(ctx, c, items[i], jr)
+			}
+		}()
+	}
+	next := 0
+dispatch:
+	for ; next < len(items) && ctx.Err() == nil; next++ {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	for i := next; i < len(items); i++ {
+		results[i] = importResult{File: items[i].File, Err: errInterrupted, NotRun: true}
+	}
+	return results
+}
+
+// importJournaled skips items the journal already marks as done and records
+// the outcome of everything else.
+func importJournaled(ctx context.Context, c apiclient.Client, item importItem, jr *journal.Journal) importResult {
+	if rec, ok := jr.Done(item.File); ok {
+		return importResult{
+			File:      item.File,
+			ProfileID: rec.Result["profile_id"],
+			Conf:      rec.Result["conf"],
+			Skipped:   true,
+		}
+	}
+
+	result := importProfile(c, item)
+	if result.Err != nil && ctx.Err() != nil {
+		// Cancelled in flight: the upload may or may not have landed, so
+		// leave it unrecorded and let a resumed import try again.
+		result.NotRun = true
+		return result
+	}
+	rec := journal.Record{Key: item.File, Status: journal.Done}
+	if result.Err != nil {
+		rec.Status = journal.Failed
+		rec.Error = result.Err.Error()
+	} else {
+		rec.Result = map[string]string{"profile_id": result.ProfileID, "conf": result.Conf}
+	}
+	if err := jr.Record(rec); err != nil {
+		cliutils.Warn().Msgf("journal %s: %v", jr.Path(), err)
+	}
+	return result
+}
+
+func importProfile(c apiclient.Client, item importItem) importResult {
+	result := importResult{File: item.File}
+
+	photo, err := common.NewPhotoFromFile(item.Path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	req := profiles.CreateRequest{
+		Photo:             photo,
+		OriginID:          item.OriginID,
+		CreateMinFacesize: item.CreateMinFacesize,
+		CreateHa:          item.CreateHa,
+		CreateJunk:        item.CreateJunk,
+	}
+	resp, err := c.Profiles().Create(req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if resp != nil {
+		result.ProfileID = firstValue(*resp, "id", "profile_id", "person_id")
+		result.Conf = firstValue(*resp, "conf")
+	}
+	return result
+}
+
+// firstValue formats the first of keys that m holds. JSON numbers decode as
+// float64, which are written out in full rather than in exponent form.
+func firstValue(m map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		switch v := m[key].(type) {
+		case nil:
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+func importItemsFromDir(dir string, defaults importItem) ([]importItem, error) {
+	var items []importItem
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !importImageExts[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		item := defaults
+		item.File = rel
+		item.Path = path
+		items = append(items, item)
+		return nil
+	})
+	sort.Slice(items, func(i, j int) bool { return items[i].File < items[j].File })
+	return items, err
+}
+
+// importItemsFromManifest reads a CSV with a header row. The "photo" column
+// is required and resolved relative to the manifest; origin_id,
+// create_min_facesize, create_ha and create_junk override the flags per row.
+func importItemsFromManifest(path string, defaults importItem) ([]importItem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read manifest header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["photo"]; !ok {
+		return nil, fmt.Errorf("manifest %s has no photo column", path)
+	}
+
+	base := filepath.Dir(path)
+	var items []importItem
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		item := defaults
+		item.File = field("photo")
+		if item.File == "" {
+			return nil, fmt.Errorf("manifest line %d: photo is empty", line)
+		}
+		item.Path = item.File
+		if !filepath.IsAbs(item.Path) {
+			item.Path = filepath.Join(base, item.Path)
+		}
+		if v := field("origin_id"); v != "" {
+			if item.OriginID, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("manifest line %d: invalid origin_id %q", line, v)
+			}
+		}
+		if v := field("create_min_facesize"); v != "" {
+			size, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("manifest line %d: invalid create_min_facesize %q", line, v)
+			}
+			item.CreateMinFacesize = intPtr(size)
+		}
+		if v := field("create_ha"); v != "" {
+			allow, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("manifest line %d: invalid create_ha %q", line, v)
+			}
+			item.CreateHa = boolPtr(allow)
+		}
+		if v := field("create_junk"); v != "" {
+			allow, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("manifest line %d: invalid create_junk %q", line, v)
+			}
+			item.CreateJunk = boolPtr(allow)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func writeImportResults(path string, results []importResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"file", "profile_id", "conf", "error"}); err != nil {
+		f.Close()
+		return err
+	}
+	for _, r := range results {
+		errText := ""
+		if r.Err != nil {
+			errText = r.Err.Error()
+		}
+		if err := w.Write([]string{r.File, r.ProfileID, r.Conf, errText}); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
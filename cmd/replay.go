@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/serptech/serp-cli/transport"
+	"github.com/spf13/cobra"
+)
+
+// replayToken stands in for credentials when replaying, since no request
+// reaches the API.
+const replayToken = "replay"
+
+func newReplayCmd() *cobra.Command {
+	replayCmd := &cobra.Command{
+		Use:   "replay FILE [-- COMMAND...]",
+		Short: "Run a command against API traffic recorded with --record",
+		Long: `Answers API requests from a HAR file recorded with --record instead of the
+network, so a problem can be reproduced offline. Without a command the recorded
+command line is run again. Requests are matched to recorded ones by method,
+path and query in the order they were recorded.`,
+		Example: `  serptech origins list --all --record traffic.har
+  serptech replay traffic.har
+  serptech replay traffic.har -- origins list --all --format table`,
+		Args: usageArgs(cobra.MinimumNArgs(1)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			har, err := transport.ReadHAR(args[0])
+			if err != nil {
+				return &usageError{msg: err.Error()}
+			}
+			command := args[1:]
+			if len(command) == 0 {
+				command = har.Log.Command
+			}
+			if len(command) == 0 {
+				return usageErrorf("%s does not record a command; give one after --", args[0])
+			}
+			if command[0] == cmd.Name() {
+				return usageErrorf("cannot replay the replay command")
+			}
+			if replayer == nil {
+				return fmt.Errorf("replay is not available without the CLI transport")
+			}
+
+			if os.Getenv("SERP_ACCESS_TOKEN") == "" {
+				if err := os.Setenv("SERP_ACCESS_TOKEN", replayToken); err != nil {
+					return err
+				}
+			}
+			replayer.Load(har)
+			defer replayer.Load(nil)
+
+			fmt.Fprintf(stderr, "replaying %d recorded requests: serptech %s\n", len(har.Log.Entries), strings.Join(command, " "))
+			if code := run(cmd.Context(), clients, command, stdout, stderr); code != ExitOK {
+				return &reportedError{code: code}
+			}
+			return nil
+		},
+	}
+
+	return replayCmd
+}
@@ -1,51 +1,183 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
 
+	"github.com/serptech/serp-cli/apiclient"
+	"github.com/serptech/serp-cli/printer"
+	"github.com/serptech/serp-cli/redact"
+	"github.com/serptech/serp-cli/transport"
 	cliutils "github.com/serptech/serp-cli/utils"
-	serpUtils "github.com/serptech/serp-go/utils"
 	"github.com/spf13/cobra"
 )
 
 var (
 	outputPath      string
 	limit           int
+	limitSet        bool
 	offset          int
+	fetchAll        bool
+	maxItems        int
 	flagAccessToken string
 	flagRootToken   string
 	debug           bool
 	baseURL         string
+	contextName     string
+	formatValue     string
+	outputFormat    printer.Format
+	outputResource  string
+	outputQuery     string
+	outputFields    string
+
+	outputTemplateText string
+	outputTemplateFile string
+	outputTemplate     *template.Template
+
+	errorFormat   string
+	commandAction string
+
+	retries            int
+	retryMaxWait       time.Duration
+	retryNonIdempotent bool
+
+	rateValue   string
+	concurrency int
+
+	requestTimeout time.Duration
+
+	recordPath        string
+	recordElideImages bool
+
+	logLevel  string
+	logFormat string
+	logPath   string
 )
 
-var rootCmd = &cobra.Command{
-	Use:     "serptech",
-	Short:   "SERP is a real-time facial recognition platform.",
-	Version: cliutils.Version,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		if flagAccessToken != "" {
-			ifErrorExit(os.Setenv("SERP_ACCESS_TOKEN", flagAccessToken))
-		}
+// stdin, stdout and stderr are the standard streams of a command. run swaps
+// the output streams so the output of a single invocation can be captured.
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
 
-		if flagRootToken != "" {
-			ifErrorExit(os.Setenv("SERP_ROOT_TOKEN", flagRootToken))
-		}
+// NewRootCmd builds the complete command tree, creating API clients with
+// factory. Flags are registered afresh on every call, which also resets the
+// package-level variables bound to them.
+func NewRootCmd(factory apiclient.Factory) *cobra.Command {
+	clients = factory
+	rootCmd := &cobra.Command{
+		Use:     "serptech",
+		Short:   "SERP is a real-time facial recognition platform.",
+		Version: cliutils.Version,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if errorFormat != errorFormatText && errorFormat != errorFormatJSON {
+				invalid := errorFormat
+				errorFormat = errorFormatText
+				return usageErrorf("unknown error format %q, expected text or json", invalid)
+			}
+			if err := configureLog(cmd); err != nil {
+				return err
+			}
+			if err := loadEnvFiles(cmd); err != nil {
+				return err
+			}
 
-		if os.Getenv("SERP_ACCESS_TOKEN") == "" {
-			if root := os.Getenv("SERP_ROOT_TOKEN"); root != "" {
-				ifErrorExit(os.Setenv("SERP_ACCESS_TOKEN", root))
+			format, err := printer.ParseFormat(formatValue)
+			if err != nil {
+				return &usageError{msg: err.Error()}
 			}
-		}
-		if baseURL != "" {
-			ifErrorExit(os.Setenv("SERP_BASE_URL", baseURL))
-		}
-		if debug {
-			ifErrorExit(os.Setenv("SERP_DEBUG", fmt.Sprintf("%v", debug)))
-			serpUtils.Warn().Msgf("%v", os.Environ())
-		}
-	},
-	Long: `
+			outputFormat = format
+			outputResource = resourceOf(cmd)
+			limitSet = cmd.Flags().Changed("limit")
+			if len(cmd.ValidArgs) > 0 && len(args) > 0 {
+				commandAction = args[0]
+			}
+			setTokenNeed(cmd, commandAction)
+			if err := applyContext(cmd); err != nil {
+				return err
+			}
+			if outputTemplate, err = loadTemplate(); err != nil {
+				return &usageError{msg: err.Error()}
+			}
+
+			if flagAccessToken != "" {
+				if err := setSetting("SERP_ACCESS_TOKEN", flagAccessToken, "flag"); err != nil {
+					return err
+				}
+			}
+
+			if flagRootToken != "" {
+				if err := setSetting("SERP_ROOT_TOKEN", flagRootToken, "flag"); err != nil {
+					return err
+				}
+			}
+
+			if baseURL != "" {
+				if err := setSetting("SERP_BASE_URL", baseURL, "flag"); err != nil {
+					return err
+				}
+			}
+			if maxItems > 0 {
+				fetchAll = true
+			}
+			if retries < 0 {
+				return usageErrorf("--retries must not be negative")
+			}
+			if retrier != nil {
+				retrier.SetPolicy(transport.RetryPolicy{
+					Retries:       retries,
+					MaxWait:       retryMaxWait,
+					NonIdempotent: retryNonIdempotent,
+				})
+			}
+
+			var interval time.Duration
+			if rateValue != "" {
+				if interval, err = transport.ParseRate(rateValue); err != nil {
+					return &usageError{msg: err.Error()}
+				}
+			}
+			if concurrency < 0 {
+				return usageErrorf("--concurrency must not be negative")
+			}
+			if limiter != nil {
+				limiter.SetRate(interval)
+				limiter.SetConcurrency(concurrency)
+			}
+			if requestTimeout < 0 {
+				return usageErrorf("--timeout must not be negative")
+			}
+			if timeout != nil {
+				timeout.SetTimeout(requestTimeout)
+			}
+			redact.AddSecret(os.Getenv("SERP_ACCESS_TOKEN"), os.Getenv("SERP_ROOT_TOKEN"))
+			if recordPath != "" && cmd.CommandPath() == "serptech replay" {
+				return usageErrorf("--record cannot be used with replay; record the replayed command instead")
+			}
+			if recordPath != "" && recorder != nil {
+				recorder.Start(transport.RecordOptions{ElideImages: recordElideImages})
+				recording = true
+			}
+			if debug {
+				if err := setSetting("SERP_DEBUG", fmt.Sprintf("%v", debug), "flag"); err != nil {
+					return err
+				}
+			}
+			cliutils.Debug().Strs("env", redact.Environ(serpEnviron())).Msg("serptech " + cliutils.Version)
+			return nil
+		},
+		Long: `
 	
          # ##########                                                              
       # ## ############ #                                                          
@@ -60,32 +192,274 @@ var rootCmd = &cobra.Command{
          ########### ##                                                             
 
 `,
-}
-
-func ifErrorExit(err error) {
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
 	}
-}
 
-func printAndExit(err string) {
-	fmt.Println(err)
-	os.Exit(1)
-}
-
-func Execute() {
 	rootCmd.SilenceUsage = true
-	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "debug cli and client")
+	rootCmd.SilenceErrors = true
+	rootCmd.SetFlagErrorFunc(flagUsageError)
+	rootCmd.SetOut(stdout)
+	rootCmd.SetErr(stderr)
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "debug cli and client (same as --log-level debug)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "log level: trace|debug|info|warn|error|disabled")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", cliutils.LogConsole, "log format: console|json")
+	rootCmd.PersistentFlags().StringVar(&logPath, "log-file", "", "append logs to this file instead of stderr")
+	rootCmd.PersistentFlags().StringVar(&errorFormat, "error-format", errorFormatText, "format of errors printed to stderr: text|json")
 	rootCmd.PersistentFlags().StringVar(&flagAccessToken, "token", "", "serptech.ru access token (SERP_ACCESS_TOKEN)")
 	rootCmd.PersistentFlags().StringVar(&flagRootToken, "root-token", "", "root API token (SERP_ROOT_TOKEN)")
+	rootCmd.PersistentFlags().BoolVar(&allowRootFallback, "allow-root-fallback", false, "use the root token for commands that take an access token when none is set")
 	rootCmd.PersistentFlags().StringVar(&baseURL, "base-url", "", "serptech.ru API base URL override")
+	rootCmd.PersistentFlags().StringArrayVar(&envFiles, "env-file", nil, "load variables the environment leaves unset from this `file` instead of ./.env (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "named connection context from the config file (SERP_CONTEXT)")
 	rootCmd.PersistentFlags().StringVarP(&outputPath, "output", "o", "", "path to file for writing output result")
+	rootCmd.PersistentFlags().StringVar(&formatValue, "format", string(printer.JSON), "output format: "+printer.FormatNames())
+	rootCmd.PersistentFlags().StringVar(&outputQuery, "query", "", "JMESPath expression, or JSONPath starting with $, applied to the result")
+	rootCmd.PersistentFlags().StringVar(&outputFields, "fields", "", "comma-separated fields to keep in every record, e.g. id,name")
+	rootCmd.PersistentFlags().StringVar(&outputTemplateText, "template", "", "Go text/template used to render the result, e.g. '{{range .results}}{{.id}}{{\"\\n\"}}{{end}}'")
+	rootCmd.PersistentFlags().StringVar(&outputTemplateFile, "template-file", "", "path to a Go text/template used to render the result")
 	rootCmd.PersistentFlags().IntVar(&limit, "limit", 20, "the number of output items, maximum 1000 entries per request")
 	rootCmd.PersistentFlags().IntVar(&offset, "offset", 0, "a sequential number of an output item, to return a sampling after this one")
+	rootCmd.PersistentFlags().BoolVar(&fetchAll, "all", false, "walk every page of a list command and output the merged items")
+	rootCmd.PersistentFlags().IntVar(&maxItems, "max-items", 0, "stop a list command after this many items (implies --all)")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", 3, "retry idempotent requests this many times on network errors, 429 and 5xx responses")
+	rootCmd.PersistentFlags().DurationVar(&retryMaxWait, "retry-max-wait", 30*time.Second, "longest wait between retries; a longer Retry-After stops retrying")
+	rootCmd.PersistentFlags().BoolVar(&retryNonIdempotent, "retry-non-idempotent", false, "also retry requests with side effects, such as profile creation")
+	rootCmd.PersistentFlags().StringVar(&rateValue, "rate", "", "limit API requests to this rate, e.g. 20/s or 600/m")
+	rootCmd.PersistentFlags().IntVar(&concurrency, "concurrency", 0, "maximum number of API requests in flight (0 means unlimited)")
+	rootCmd.PersistentFlags().StringVar(&recordPath, "record", "", "save every API request and response to this HAR file, with secrets redacted")
+	rootCmd.PersistentFlags().BoolVar(&recordElideImages, "record-elide-images", false, "leave photos out of the --record file")
+	rootCmd.PersistentFlags().DurationVar(&requestTimeout, "timeout", 60*time.Second, "give up on a single API request after this long (0 means no limit)")
+
+	rootCmd.AddCommand(
+		newAPICmd(),
+		newAuthCmd(),
+		newConfigCmd(),
+		newEntriesCmd(),
+		newEnvCmd(),
+		newLoginCmd(),
+		newLogoutCmd(),
+		newMockCmd(),
+		newOriginsCmd(),
+		newProfilesCmd(),
+		newReplayCmd(),
+		newTokensCmd(),
+		newUsersCmd(),
+		newUtilityCmd(),
+		newVersionCmd(),
+	)
+	return rootCmd
+}
+
+// clients creates the API clients used by commands.
+var clients = apiclient.Default
+
+// observer records failed HTTP exchanges for error classification, canceler
+// aborts requests when the command is interrupted, retrier repeats transient
+// failures, limiter paces every attempt and timeout bounds each of them.
+// recorder captures the attempts for --record and replayer answers them from
+// a recording instead of the network.
+var (
+	observer *transport.Observer
+	canceler *transport.Canceler
+	retrier  *transport.Retrier
+	limiter  *transport.Limiter
+	recorder *transport.Recorder
+	timeout  *transport.Timeout
+	replayer *transport.Replayer
+)
+
+// recording is set while the current command is recorded with --record.
+var recording bool
+
+// Execute runs the command tree and exits with one of the Exit* codes. The
+// first SIGINT or SIGTERM cancels the command; a second one kills it.
+func Execute() {
+	installTransport()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	code := run(ctx, apiclient.Default, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	if code != ExitOK {
+		os.Exit(code)
+	}
+}
+
+// installTransport wraps the default HTTP transport shared by the API client
+// and the api command.
+func installTransport() {
+	transport.Install(func(base http.RoundTripper) http.RoundTripper {
+		replayer = transport.NewReplayer(base)
+		timeout = transport.NewTimeout(replayer)
+		recorder = transport.NewRecorder(timeout)
+		tracer := transport.NewTracer(recorder)
+		tracer.Log = func(t transport.Trace) {
+			event := cliutils.Debug().
+				Str("method", t.Method).
+				Str("url", t.URL).
+				Str("request_id", t.RequestID).
+				Dur("duration", t.Duration).
+				Int64("request_bytes", t.RequestBytes).
+				Int64("response_bytes", t.ResponseBytes)
+			if t.Err != nil {
+				event = event.Str("error", redact.String(t.Err.Error()))
+			} else {
+				event = event.Int("status", t.Status)
+			}
+			event.Msg("http request")
+		}
+		limiter = transport.NewLimiter(tracer)
+		retrier = transport.NewRetrier(limiter)
+		retrier.OnRetry = func(req *http.Request, attempt int, wait time.Duration, reason string) {
+			cliutils.Debug().Str("request_id", req.Header.Get("X-Request-ID")).Msgf("retrying %s %s in %s (attempt %d): %s", req.Method, req.URL.Path, wait.Round(time.Millisecond), attempt+1, redact.String(reason))
+		}
+		canceler = transport.NewCanceler(retrier)
+		observer = transport.NewObserver(canceler)
+		return observer
+	})
+}
+
+// run executes a freshly built command tree with args and returns the exit
+// code. Command output goes to out and errors to errOut. Cancelling ctx
+// aborts the command's API requests and makes it exit with ExitInterrupted.
+func run(ctx context.Context, factory apiclient.Factory, args []string, out, errOut io.Writer) int {
+	prevOut, prevErr := stdout, stderr
+	stdout, stderr = out, errOut
+	defer func() { stdout, stderr = prevOut, prevErr }()
+	// replay runs a nested command line; leave the state of the outer one
+	// as it was.
+	prevAction, prevRecording, prevLookup, prevLogFile := commandAction, recording, pendingLookup, logFile
+	restoreLog := cliutils.SaveLog()
+	defer func() {
+		closeLog()
+		commandAction, recording, pendingLookup, logFile = prevAction, prevRecording, prevLookup, prevLogFile
+		restoreLog()
+	}()
+	commandAction = ""
+	recording = false
+	pendingLookup = nil
+	logFile = nil
+	prevLoaded, prevDotenv, prevSources := loadedEnv, dotenvKeys, settingSources
+	loadedEnv, dotenvKeys, settingSources = nil, map[string]bool{}, map[string]string{}
+	defer func() {
+		unloadEnvFiles()
+		loadedEnv, dotenvKeys, settingSources = prevLoaded, prevDotenv, prevSources
+	}()
+	if observer != nil {
+		observer.Reset()
+	}
+	if canceler != nil {
+		prevCtx := canceler.Context()
+		canceler.SetContext(ctx)
+		defer canceler.SetContext(prevCtx)
+	}
+
+	rootCmd := NewRootCmd(factory)
+	rootCmd.SetArgs(args)
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	if recording {
+		recording = false
+		if saveErr := saveRecording(recordPath, args); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	if err == nil {
+		return ExitOK
+	}
+	// Cobra looks up the command before parsing any flags and reports an
+	// unknown one with a plain error.
+	if cmd != nil && !cmd.Flags().Parsed() {
+		err = &usageError{msg: err.Error()}
+	}
+	var reported *reportedError
+	if errors.As(err, &reported) {
+		return reported.code
+	}
+	if ctx.Err() != nil && !errors.Is(err, errInterrupted) {
+		err = fmt.Errorf("%w: %v", errInterrupted, err)
+	}
+	code := exitCode(err)
+	printError(stderr, cmd, err, code)
+	return code
+}
+
+// serpEnviron returns the SERP_* variables of the environment.
+func serpEnviron() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "SERP_") {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// saveRecording writes the exchanges recorded for args to path.
+func saveRecording(path string, args []string) error {
+	har := recorder.Stop()
+	har.Log.Creator = transport.HARCreator{Name: "serptech", Version: cliutils.Version}
+	har.Log.Command = recordedCommand(args)
+	if err := transport.WriteHAR(path, har); err != nil {
+		return fmt.Errorf("write recording: %w", err)
+	}
+	return nil
+}
+
+// recordedCommand returns args without the recording flags and with secrets
+// redacted, so replaying it neither overwrites the recording nor needs them.
+func recordedCommand(args []string) []string {
+	out := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--record":
+			i++
+			continue
+		case strings.HasPrefix(arg, "--record=") || strings.HasPrefix(arg, "--record-elide-images"):
+			continue
+		}
+		out = append(out, redact.Secrets(arg))
+	}
+	return out
+}
+
+// logFile is the --log-file of the current command, if any.
+var logFile *os.File
+
+// configureLog sets up logging for cmd from the --log-* flags. --debug is
+// short for --log-level debug unless a level is given explicitly.
+func configureLog(cmd *cobra.Command) error {
+	opts := cliutils.LogOptions{
+		Level:  logLevel,
+		Format: logFormat,
+		Out:    stderr,
+		Fields: map[string]string{"command": cmd.CommandPath()},
+	}
+	if !cmd.Flags().Changed("log-level") {
+		// Without --log-level, keep the startup level so that SERP_DEBUG
+		// still works.
+		opts.Level = cliutils.DefaultLogOptions().Level
+		if debug {
+			opts.Level = "debug"
+		}
+	}
+	if logPath != "" {
+		f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return usageErrorf("open log file: %v", err)
+		}
+		logFile = f
+		opts.Out = f
+	}
+	if err := cliutils.ConfigureLog(opts); err != nil {
+		return &usageError{msg: err.Error()}
+	}
+	return nil
+}
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+// closeLog closes the --log-file and goes back to the startup logger.
+func closeLog() {
+	_ = cliutils.ConfigureLog(cliutils.DefaultLogOptions())
+	if logFile != nil {
+		logFile.Close()
+		logFile = nil
 	}
 }
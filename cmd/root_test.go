@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/serptech/serp-cli/apiclient"
+	cliutils "github.com/serptech/serp-cli/utils"
+)
+
+// TestRunRestoresOuterState runs a command the way replay does, nested in
+// another one, and checks that the outer command gets its state back.
+func TestRunRestoresOuterState(t *testing.T) {
+	h := newHarness(t, nil, nil)
+	outerLog := filepath.Join(h.dir, "outer.log")
+	f, err := os.OpenFile(outerLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cliutils.ConfigureLog(cliutils.LogOptions{Level: "info", Out: f}); err != nil {
+		t.Fatal(err)
+	}
+	outerCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	commandAction, recording, logFile = "list", true, f
+	canceler.SetContext(outerCtx)
+	defer func() {
+		commandAction, recording = "", false
+		canceler.SetContext(nil)
+		closeLog()
+	}()
+
+	var out bytes.Buffer
+	args := []string{"origins", "get", "--id", "1", "--log-level", "debug", "--log-file", filepath.Join(h.dir, "inner.log")}
+	if code := run(context.Background(), apiclient.Default, args, &out, &out); code != ExitOK {
+		t.Fatalf("exit code %d: %s", code, out.String())
+	}
+
+	if commandAction != "list" || !recording || logFile != f {
+		t.Errorf("outer state lost: action %q, recording %v, log file %v", commandAction, recording, logFile)
+	}
+	if canceler.Context() != outerCtx {
+		t.Error("outer context lost")
+	}
+	cliutils.Info().Msg("outer after nested")
+	data, err := os.ReadFile(outerLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "outer after nested") {
+		t.Errorf("outer log lost the logger:\n%s", data)
+	}
+}
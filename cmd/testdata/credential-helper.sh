@@ -0,0 +1,15 @@
+#!/bin/sh
+# Credential helper used by the tests. It keeps the tokens it is given in
+# $SERP_TEST_CREDENTIALS, whichever context they belong to.
+store=${SERP_TEST_CREDENTIALS:?}
+case "$1" in
+get)
+	if [ -f "$store" ]; then cat "$store"; fi
+	;;
+store)
+	grep '_token=' > "$store"
+	;;
+erase)
+	rm -f "$store"
+	;;
+esac
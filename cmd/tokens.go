@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/serptech/serp-go/api/client"
 	"github.com/serptech/serp-go/api/common"
 	"github.com/serptech/serp-go/api/tokens"
 	"github.com/spf13/cobra"
@@ -20,130 +19,190 @@ var (
 	tokensStreamFilterSpace int
 )
 
-var tokensCmd = &cobra.Command{
-	Use:   "tokens",
-	Short: "Manage API tokens",
+func newTokensAccessCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "access",
+		Short: "Manage access tokens",
+	}
 }
 
-var tokensAccessCmd = &cobra.Command{
-	Use:   "access",
-	Short: "Manage access tokens",
+func newTokensAccessListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List access tokens",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			filterSpace := cmd.Flag("space-id").Changed
+			return writeList(func(pageLimit, pageOffset int) (interface{}, error) {
+				query := common.NewPaginationQuery(pageLimit, pageOffset)
+				if filterSpace {
+					query["space_id"] = tokensAccessFilterSpace
+				}
+				resp, err := c.Tokens().ListAccess(query)
+				if err != nil {
+					return nil, fmt.Errorf("list access tokens: %w", err)
+				}
+				return resp, nil
+			})
+		},
+	}
 }
 
-var tokensAccessListCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List access tokens",
-	Run: func(cmd *cobra.Command, args []string) {
-		c, err := client.NewClient()
-		ifErrorExit(err)
-
-		query := common.NewPaginationQuery(limit, offset)
-		if cmd.Flag("space-id").Changed {
-			query["space_id"] = tokensAccessFilterSpace
-		}
-
-		resp, err := c.Tokens().ListAccess(query)
-		ifErrorExit(err)
-		writeOutput(resp)
-	},
+func newTokensAccessCreateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "create",
+		Short: "Create access token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			req := tokens.CreateTokenRequest{Permanent: tokensAccessPermanent}
+
+			resp, err := c.Tokens().CreateAccess(req)
+			if err != nil {
+				return fmt.Errorf("create access token: %w", err)
+			}
+			return writeOutput(resp)
+		},
+	}
 }
 
-var tokensAccessCreateCmd = &cobra.Command{
-	Use:   "create",
-	Short: "Create access token",
-	Run: func(cmd *cobra.Command, args []string) {
-		c, err := client.NewClient()
-		ifErrorExit(err)
-
-		req := tokens.CreateTokenRequest{Permanent: tokensAccessPermanent}
-
-		resp, err := c.Tokens().CreateAccess(req)
-		ifErrorExit(err)
-		writeOutput(resp)
-	},
+func newTokensAccessDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete",
+		Short: "Delete access token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(tokensAccessKey) == "" {
+				return usageErrorf("token key is required")
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			if err := c.Tokens().DeleteAccess(tokensAccessKey); err != nil {
+				return fmt.Errorf("delete access token: %w", err)
+			}
+			fmt.Fprintf(stdout, "access token %s successfully deleted\n", tokensAccessKey)
+			return nil
+		},
+	}
 }
 
-var tokensAccessDeleteCmd = &cobra.Command{
-	Use:   "delete",
-	Short: "Delete access token",
-	Run: func(cmd *cobra.Command, args []string) {
-		if strings.TrimSpace(tokensAccessKey) == "" {
-			printAndExit("token key is required")
-		}
-		c, err := client.NewClient()
-		ifErrorExit(err)
-		ifErrorExit(c.Tokens().DeleteAccess(tokensAccessKey))
-		fmt.Printf("access token %s successfully deleted\n", tokensAccessKey)
-	},
+func newTokensStreamsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "streams",
+		Short: "Manage stream tokens",
+	}
 }
 
-var tokensStreamsCmd = &cobra.Command{
-	Use:   "streams",
-	Short: "Manage stream tokens",
+func newTokensStreamsListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List stream tokens",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			filterSpace := cmd.Flag("space-id").Changed
+			return writeList(func(pageLimit, pageOffset int) (interface{}, error) {
+				query := common.NewPaginationQuery(pageLimit, pageOffset)
+				if filterSpace {
+					query["space_id"] = tokensStreamFilterSpace
+				}
+				resp, err := c.Tokens().ListStreams(query)
+				if err != nil {
+					return nil, fmt.Errorf("list stream tokens: %w", err)
+				}
+				return resp, nil
+			})
+		},
+	}
 }
 
-var tokensStreamsListCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List stream tokens",
-	Run: func(cmd *cobra.Command, args []string) {
-		c, err := client.NewClient()
-		ifErrorExit(err)
-
-		query := common.NewPaginationQuery(limit, offset)
-		if cmd.Flag("space-id").Changed {
-			query["space_id"] = tokensStreamFilterSpace
-		}
-
-		resp, err := c.Tokens().ListStreams(query)
-		ifErrorExit(err)
-		writeOutput(resp)
-	},
+func newTokensStreamsCreateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "create",
+		Short: "Create stream token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			req := tokens.CreateTokenRequest{Permanent: tokensStreamPermanent}
+
+			resp, err := c.Tokens().CreateStream(req)
+			if err != nil {
+				return fmt.Errorf("create stream token: %w", err)
+			}
+			return writeOutput(resp)
+		},
+	}
 }
 
-var tokensStreamsCreateCmd = &cobra.Command{
-	Use:   "create",
-	Short: "Create stream token",
-	Run: func(cmd *cobra.Command, args []string) {
-		c, err := client.NewClient()
-		ifErrorExit(err)
-
-		req := tokens.CreateTokenRequest{Permanent: tokensStreamPermanent}
-
-		resp, err := c.Tokens().CreateStream(req)
-		ifErrorExit(err)
-		writeOutput(resp)
-	},
+func newTokensStreamsDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete",
+		Short: "Delete stream token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(tokensStreamKey) == "" {
+				return usageErrorf("token key is required")
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			if err := c.Tokens().DeleteStream(tokensStreamKey); err != nil {
+				return fmt.Errorf("delete stream token: %w", err)
+			}
+			fmt.Fprintf(stdout, "stream token %s successfully deleted\n", tokensStreamKey)
+			return nil
+		},
+	}
 }
 
-var tokensStreamsDeleteCmd = &cobra.Command{
-	Use:   "delete",
-	Short: "Delete stream token",
-	Run: func(cmd *cobra.Command, args []string) {
-		if strings.TrimSpace(tokensStreamKey) == "" {
-			printAndExit("token key is required")
-		}
-		c, err := client.NewClient()
-		ifErrorExit(err)
-		ifErrorExit(c.Tokens().DeleteStream(tokensStreamKey))
-		fmt.Printf("stream token %s successfully deleted\n", tokensStreamKey)
-	},
-}
+func newTokensCmd() *cobra.Command {
+	tokensCmd := &cobra.Command{
+		Use:         "tokens",
+		Short:       "Manage API tokens",
+		Annotations: map[string]string{"resource": "tokens"},
+	}
+
+	tokensAccessCmd := newTokensAccessCmd()
+	tokensAccessListCmd := newTokensAccessListCmd()
+	tokensAccessCreateCmd := newTokensAccessCreateCmd()
+	tokensAccessDeleteCmd := newTokensAccessDeleteCmd()
+	tokensAccessRotateCmd := newTokensAccessRotateCmd()
+	tokensStreamsCmd := newTokensStreamsCmd()
+	tokensStreamsListCmd := newTokensStreamsListCmd()
+	tokensStreamsCreateCmd := newTokensStreamsCreateCmd()
+	tokensStreamsDeleteCmd := newTokensStreamsDeleteCmd()
 
-func init() {
 	tokensAccessListCmd.Flags().IntVar(&tokensAccessFilterSpace, "space-id", 0, "filter by space identifier")
 
 	tokensAccessCreateCmd.Flags().BoolVar(&tokensAccessPermanent, "permanent", false, "create permanent token")
 
 	tokensAccessDeleteCmd.Flags().StringVar(&tokensAccessKey, "key", "", "token key")
 
+	tokensAccessRotateCmd.Flags().StringVar(&tokensRotateKey, "key", "", "key of the token to replace")
+	tokensAccessRotateCmd.Flags().BoolVar(&tokensRotatePermanent, "permanent", false, "make the new token permanent")
+
 	tokensStreamsListCmd.Flags().IntVar(&tokensStreamFilterSpace, "space-id", 0, "filter by space identifier")
 
 	tokensStreamsCreateCmd.Flags().BoolVar(&tokensStreamPermanent, "permanent", false, "create permanent token")
 
 	tokensStreamsDeleteCmd.Flags().StringVar(&tokensStreamKey, "key", "", "token key")
 
-	tokensAccessCmd.AddCommand(tokensAccessListCmd, tokensAccessCreateCmd, tokensAccessDeleteCmd)
+	tokensAccessCmd.AddCommand(tokensAccessListCmd, tokensAccessCreateCmd, tokensAccessDeleteCmd, tokensAccessRotateCmd)
 	tokensStreamsCmd.AddCommand(tokensStreamsListCmd, tokensStreamsCreateCmd, tokensStreamsDeleteCmd)
 	tokensCmd.AddCommand(tokensAccessCmd, tokensStreamsCmd)
-	rootCmd.AddCommand(tokensCmd)
+	return tokensCmd
 }
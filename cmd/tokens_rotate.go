@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/serptech/serp-cli/config"
+	"github.com/serptech/serp-cli/credential"
+	"github.com/serptech/serp-cli/redact"
+	"github.com/serptech/serp-go/api/client"
+	"github.com/serptech/serp-go/api/tokens"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tokensRotateKey       string
+	tokensRotatePermanent bool
+)
+
+func newTokensAccessRotateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rotate",
+		Short: "Replace an access token with a new one",
+		Long: `Creates a new access token, checks that it works, saves it wherever the
+current context keeps the old one (config file, credential helper or saved
+login) and only then deletes the old key. When a step before the new token is
+saved fails, the steps before it are undone, so the old key keeps working and
+no new token is left behind. When only deleting the old key fails, the new
+token stays in place and the old key has to be deleted by hand.
+
+Tokens given with --token, the environment or an env file cannot be updated;
+the command tells which one to change by hand.`,
+		Example: `  serptech tokens access rotate --key "$SERP_ACCESS_TOKEN"
+  serptech --context prod tokens access rotate --key 3f2a... --permanent`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			oldKey := strings.TrimSpace(tokensRotateKey)
+			if oldKey == "" {
+				return usageErrorf("token key is required")
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			// undo holds the steps that revert what was done so far, run in
+			// reverse order when a later step fails.
+			var undo []func() error
+			rollback := func(err error) error {
+				for i := len(undo) - 1; i >= 0; i-- {
+					if undoErr := undo[i](); undoErr != nil {
+						fmt.Fprintf(stderr, "rollback failed: %v\n", undoErr)
+					}
+				}
+				return err
+			}
+
+			resp, err := c.Tokens().CreateAccess(tokens.CreateTokenRequest{Permanent: tokensRotatePermanent})
+			if err != nil {
+				return fmt.Errorf("create access token: %w", err)
+			}
+			newKey, err := tokenKey(resp)
+			if err != nil {
+				return err
+			}
+			redact.AddSecret(newKey)
+			undo = append(undo, func() error {
+				if err := c.Tokens().DeleteAccess(newKey); err != nil {
+					return fmt.Errorf("delete new access token %s: %w", newKey, err)
+				}
+				fmt.Fprintf(stderr, "rolled back: deleted new access token %s\n", newKey)
+				return nil
+			})
+			fmt.Fprintf(stderr, "created access token %s\n", newKey)
+
+			fresh := clients.NewClientWithToken(newKey)
+			me, err := fresh.Users().Me()
+			if err != nil {
+				return rollback(fmt.Errorf("verify new access token: %w", err))
+			}
+			fmt.Fprintf(stderr, "verified new access token as %s\n", identityOf(me))
+
+			note, restore, err := storeRotatedToken(cmd, oldKey, newKey)
+			if err != nil {
+				return rollback(fmt.Errorf("save new access token: %w", err))
+			}
+			if restore != nil {
+				undo = append(undo, func() error {
+					if err := restore(); err != nil {
+						return fmt.Errorf("put back %s: %w", oldKey, err)
+					}
+					fmt.Fprintf(stderr, "rolled back: put back access token %s\n", oldKey)
+					return nil
+				})
+			}
+			fmt.Fprintln(stderr, note)
+
+			if os.Getenv("SERP_ACCESS_TOKEN") == oldKey {
+				if err := setSetting("SERP_ACCESS_TOKEN", newKey, settingSource("SERP_ACCESS_TOKEN")); err != nil {
+					return err
+				}
+			}
+			// The new token works and is saved, so a failure from here on
+			// must not take it away again.
+			if err := fresh.Tokens().DeleteAccess(oldKey); err != nil {
+				fmt.Fprintf(stderr, "the new access token is in use; delete the old one by hand with: serptech tokens access delete --key %s\n", oldKey)
+				return fmt.Errorf("delete old access token: %w", err)
+			}
+			fmt.Fprintf(stderr, "deleted access token %s\n", oldKey)
+			return writeOutput(resp)
+		},
+	}
+}
+
+// tokenKey returns the key of a token in a create response.
+func tokenKey(resp *client.Object) (string, error) {
+	if resp != nil {
+		if key, ok := (*resp)["key"].(string); ok && key != "" {
+			return key, nil
+		}
+	}
+	return "", errors.New("create access token: the response has no key")
+}
+
+// storeRotatedToken replaces oldKey with newKey where the current context
+// keeps its access token. It returns a message for the user and a function
+// that puts oldKey back, or nil when nothing was changed.
+func storeRotatedToken(cmd *cobra.Command, oldKey, newKey string) (string, func() error, error) {
+	if os.Getenv("SERP_ACCESS_TOKEN") != oldKey {
+		return "the rotated key is not the access token in use; no credentials updated", nil, nil
+	}
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		return "", nil, err
+	}
+	name, _ := selectedContext(cfg)
+
+	switch source := settingSource("SERP_ACCESS_TOKEN"); source {
+	case "config":
+		ctx, err := cfg.Context(name)
+		if err != nil {
+			return "", nil, err
+		}
+		set := func(key string) error {
+			ctx.AccessToken = key
+			return cfg.Save()
+		}
+		if err := set(newKey); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("saved new access token to context %q in %s", name, cfg.Path()), func() error { return set(oldKey) }, nil
+
+	case "credential helper":
+		ctx, err := cfg.Context(name)
+		if err != nil {
+			return "", nil, err
+		}
+		helper := credential.Helper{Command: ctx.CredentialHelper}
+		req := credentialRequest(name, ctx)
+		// The helper replaces everything it keeps for the context, so the
+		// root token has to be stored again along with the new access token.
+		current, err := helper.Get(cmd.Context(), req)
+		if err != nil {
+			return "", nil, err
+		}
+		set := func(key string) error {
+			return helper.Store(cmd.Context(), req, credential.Credentials{AccessToken: key, RootToken: current.RootToken})
+		}
+		if err := set(newKey); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("saved new access token with the credential helper of context %q", name), func() error { return set(oldKey) }, nil
+
+	case "login":
+		key := loginContext(name)
+		passphrase, err := readPassphrase(false)
+		if err != nil {
+			return "", nil, err
+		}
+		vault, err := credential.OpenVault(vaultPath(cfg), passphrase)
+		if err != nil {
+			return "", nil, &authError{msg: err.Error()}
+		}
+		var login credential.Login
+		for _, l := range vault.Logins(key) {
+			if !l.Root {
+				login = l
+			}
+		}
+		set := func(token string) error {
+			login.Token, login.SavedAt = token, time.Now().UTC()
+			vault.Set(key, login)
+			return vault.Save()
+		}
+		if err := set(newKey); err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("saved new access token for context %q to %s", key, vault.Path()), func() error { return set(oldKey) }, nil
+
+	default:
+		return fmt.Sprintf("the access token in use comes from %s; replace it with the new token there", source), nil, nil
+	}
+}
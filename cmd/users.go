@@ -2,10 +2,8 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"strings"
 
-	"github.com/serptech/serp-go/api/client"
 	"github.com/serptech/serp-go/api/common"
 	serpusers "github.com/serptech/serp-go/api/users"
 	"github.com/spf13/cobra"
@@ -21,128 +19,148 @@ const (
 	userPatch      = "patch"
 )
 
-var usersCmd = &cobra.Command{
-	Use:       "users [action]",
-	Short:     "Interact with user-related endpoints",
-	ValidArgs: []string{userMe, userListTokens, userStatistics, userList, userGet, userUpdate, userPatch},
-	Args:      cobra.MaximumNArgs(1),
-	Long:      "Available actions: me, list-tokens, statistics, list, get, update, patch.",
-	Example: `  serptech users me
+var (
+	userSearchValue   string
+	userQueryValue    string
+	userTargetID      int
+	userUsernameValue string
+	userIsActiveValue bool
+)
+
+func newUsersCmd() *cobra.Command {
+	usersCmd := &cobra.Command{
+		Use:         "users [action]",
+		Short:       "Interact with user-related endpoints",
+		Annotations: map[string]string{"resource": "users"},
+		ValidArgs:   []string{userMe, userListTokens, userStatistics, userList, userGet, userUpdate, userPatch},
+		Args:        cobra.MaximumNArgs(1),
+		Long:        "Available actions: me, list-tokens, statistics, list, get, update, patch.",
+		Example: `  serptech users me
   serptech users list-tokens
   serptech users statistics
-  serptech users list --limit 50 --query admin
+  serptech users list --limit 50 --search admin
   serptech users get --id 12
   serptech users update --id 12 --username admin --is-active=false
   serptech users patch --id 12 --is-active=true`,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) == 0 {
-			return cmd.Help()
-		}
-		action := args[0]
-		c := resolveUsersClient(action)
-
-		switch action {
-		case userMe:
-			meOut, err := c.Users().Me()
-			ifErrorExit(err)
-			writeOutput(meOut)
-		case userListTokens:
-			tokens, err := c.Tokens().ListAccess(nil)
-			ifErrorExit(err)
-			writeOutput(tokens)
-		case userStatistics:
-			resp, err := c.Users().Statistics()
-			ifErrorExit(err)
-			writeOutput(resp)
-		case userList:
-			query := common.NewPaginationQuery(limit, offset)
-			if cmd.Flag("query").Changed {
-				trimmed := strings.TrimSpace(userQueryValue)
-				if trimmed != "" {
-					query["q"] = trimmed
-				}
-			}
-			resp, err := c.Users().List(query)
-			ifErrorExit(err)
-			writeOutput(resp)
-		case userGet:
-			if userTargetID == 0 {
-				printAndExit("user id is required")
-			}
-			resp, err := c.Users().Get(userTargetID)
-			ifErrorExit(err)
-			writeOutput(resp)
-		case userUpdate:
-			if userTargetID == 0 {
-				printAndExit("user id is required")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return cmd.Help()
 			}
-			if !cmd.Flag("username").Changed {
-				printAndExit("username is required")
+			action := args[0]
+			// --query used to filter users list by username. It keeps that
+			// meaning there unless --search is given too; everywhere else it
+			// is the output query, as on every other command.
+			queryFilters := cmd.Flags().Changed("query") && action == userList && !cmd.Flags().Changed("search")
+			if cmd.Flags().Changed("query") && !queryFilters {
+				outputQuery = userQueryValue
 			}
-			if !cmd.Flag("is-active").Changed {
-				printAndExit("is-active is required")
+			c, err := newClient()
+			if err != nil {
+				return err
 			}
-			req := serpusers.UpdateUserRequest{
-				Username: strings.TrimSpace(userUsernameValue),
-				IsActive: userIsActiveValue,
-			}
-			if err := req.Validate(); err != nil {
-				printAndExit(err.Error())
-			}
-			resp, err := c.Users().Update(userTargetID, req)
-			ifErrorExit(err)
-			writeOutput(resp)
-		case userPatch:
-			if userTargetID == 0 {
-				printAndExit("user id is required")
-			}
-			var req serpusers.PartialUpdateUserRequest
-			if cmd.Flag("username").Changed {
-				trimmed := strings.TrimSpace(userUsernameValue)
-				req.Username = &trimmed
-			}
-			if cmd.Flag("is-active").Changed {
-				active := userIsActiveValue
-				req.IsActive = &active
-			}
-			if err := req.Validate(); err != nil {
-				printAndExit(err.Error())
-			}
-			resp, err := c.Users().PartialUpdate(userTargetID, req)
-			ifErrorExit(err)
-			writeOutput(resp)
-		default:
-			printAndExit(fmt.Sprintf("unsupported command %q", action))
-		}
-		return nil
-	},
-}
-
-var (
-	userQueryValue    string
-	userTargetID      int
-	userUsernameValue string
-	userIsActiveValue bool
-)
 
-func resolveUsersClient(action string) *client.Client {
-	requiresRoot := action == userList || action == userGet || action == userUpdate || action == userPatch
-	if requiresRoot {
-		rootToken := strings.TrimSpace(os.Getenv("SERP_ROOT_TOKEN"))
-		if rootToken == "" {
-			printAndExit("SERP_ROOT_TOKEN environment variable is required for this action")
-		}
-		return client.NewClientWithToken(rootToken)
+			switch action {
+			case userMe:
+				meOut, err := c.Users().Me()
+				if err != nil {
+					return fmt.Errorf("get current user: %w", err)
+				}
+				return writeOutput(meOut)
+			case userListTokens:
+				tokens, err := c.Tokens().ListAccess(nil)
+				if err != nil {
+					return fmt.Errorf("list access tokens: %w", err)
+				}
+				return writeOutput(tokens)
+			case userStatistics:
+				resp, err := c.Users().Statistics()
+				if err != nil {
+					return fmt.Errorf("user statistics: %w", err)
+				}
+				return writeOutput(resp)
+			case userList:
+				search := ""
+				switch {
+				case cmd.Flag("search").Changed:
+					search = strings.TrimSpace(userSearchValue)
+				case queryFilters:
+					fmt.Fprintln(stderr, "Flag --query has been deprecated, use --search to filter users list by username")
+					search = strings.TrimSpace(userQueryValue)
+				}
+				return writeList(func(pageLimit, pageOffset int) (interface{}, error) {
+					query := common.NewPaginationQuery(pageLimit, pageOffset)
+					if search != "" {
+						query["q"] = search
+					}
+					resp, err := c.Users().List(query)
+					if err != nil {
+						return nil, fmt.Errorf("list users: %w", err)
+					}
+					return resp, nil
+				})
+			case userGet:
+				if userTargetID == 0 {
+					return usageErrorf("user id is required")
+				}
+				resp, err := c.Users().Get(userTargetID)
+				if err != nil {
+					return fmt.Errorf("get user %d: %w", userTargetID, err)
+				}
+				return writeOutput(resp)
+			case userUpdate:
+				if userTargetID == 0 {
+					return usageErrorf("user id is required")
+				}
+				if !cmd.Flag("username").Changed {
+					return usageErrorf("username is required")
+				}
+				if !cmd.Flag("is-active").Changed {
+					return usageErrorf("is-active is required")
+				}
+				req := serpusers.UpdateUserRequest{
+					Username: strings.TrimSpace(userUsernameValue),
+					IsActive: userIsActiveValue,
+				}
+				if err := req.Validate(); err != nil {
+					return &usageError{msg: err.Error()}
+				}
+				resp, err := c.Users().Update(userTargetID, req)
+				if err != nil {
+					return fmt.Errorf("update user %d: %w", userTargetID, err)
+				}
+				return writeOutput(resp)
+			case userPatch:
+				if userTargetID == 0 {
+					return usageErrorf("user id is required")
+				}
+				var req serpusers.PartialUpdateUserRequest
+				if cmd.Flag("username").Changed {
+					trimmed := strings.TrimSpace(userUsernameValue)
+					req.Username = &trimmed
+				}
+				if cmd.Flag("is-active").Changed {
+					active := userIsActiveValue
+					req.IsActive = &active
+				}
+				if err := req.Validate(); err != nil {
+					return &usageError{msg: err.Error()}
+				}
+				resp, err := c.Users().PartialUpdate(userTargetID, req)
+				if err != nil {
+					return fmt.Errorf("update user %d: %w", userTargetID, err)
+				}
+				return writeOutput(resp)
+			default:
+				return usageErrorf("unsupported command %q", action)
+			}
+		},
 	}
-	c, err := client.NewClient()
-	ifErrorExit(err)
-	return c
-}
 
-func init() {
-	usersCmd.Flags().StringVar(&userQueryValue, "query", "", "filter users by username substring")
+	usersCmd.Flags().StringVarP(&userSearchValue, "search", "s", "", "filter users by username substring")
+	usersCmd.Flags().StringVar(&userQueryValue, "query", "", "filter users list by username substring (deprecated, use --search), or query the output")
+	usersCmd.Flags().MarkHidden("query")
 	usersCmd.Flags().IntVar(&userTargetID, "id", 0, "target user identifier")
 	usersCmd.Flags().StringVar(&userUsernameValue, "username", "", "username value")
 	usersCmd.Flags().BoolVar(&userIsActiveValue, "is-active", false, "toggle active status")
-	rootCmd.AddCommand(usersCmd)
+	return usersCmd
 }
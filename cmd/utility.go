@@ -2,10 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"strings"
 
-	"github.com/serptech/serp-go/api/client"
 	"github.com/serptech/serp-go/api/const/conf"
 	"github.com/serptech/serp-go/api/utility"
 	"github.com/spf13/cobra"
@@ -23,99 +20,149 @@ var (
 	utilityCompareLivenessTwo bool
 )
 
-var utilityCmd = &cobra.Command{
-	Use:   "utility",
-	Short: "Access utility endpoints (health, metrics, checks)",
+func newUtilityHealthCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "health",
+		Short: "Health check",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			resp, err := c.Utility().Health()
+			if err != nil {
+				return fmt.Errorf("health check: %w", err)
+			}
+			return writeOutput(resp)
+		},
+	}
 }
 
-var utilityHealthCmd = &cobra.Command{
-	Use:   "health",
-	Short: "Health check",
-	Run: func(cmd *cobra.Command, args []string) {
-		c := resolveUtilityClient(false)
-		resp, err := c.Utility().Health()
-		ifErrorExit(err)
-		writeOutput(resp)
-	},
+func newUtilityMetricsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "metrics",
+		Short: "Platform metrics",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			metrics, err := c.Utility().Metrics()
+			if err != nil {
+				return fmt.Errorf("metrics: %w", err)
+			}
+			fmt.Fprint(stdout, metrics)
+			return nil
+		},
+	}
 }
 
-var utilityMetricsCmd = &cobra.Command{
-	Use:   "metrics",
-	Short: "Platform metrics",
-	Run: func(cmd *cobra.Command, args []string) {
-		c := resolveUtilityClient(false)
-		metrics, err := c.Utility().Metrics()
-		ifErrorExit(err)
-		fmt.Print(metrics)
-	},
+func newUtilityAsmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "asm",
+		Short: "Age/sex/mood prediction",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if utilityAsmPhotoPath == "" {
+				return usageErrorf("photo is required")
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			req, err := utility.NewAsmRequest(utilityAsmPhotoPath)
+			if err != nil {
+				return fmt.Errorf("read photo: %w", err)
+			}
+			resp, err := c.Utility().Asm(req)
+			if err != nil {
+				return fmt.Errorf("asm: %w", err)
+			}
+			return writeOutput(resp)
+		},
+	}
 }
 
-var utilityAsmCmd = &cobra.Command{
-	Use:   "asm",
-	Short: "Age/sex/mood prediction",
-	Run: func(cmd *cobra.Command, args []string) {
-		if utilityAsmPhotoPath == "" {
-			printAndExit("photo is required")
-		}
-		c := resolveUtilityClient(false)
-		req, err := utility.NewAsmRequest(utilityAsmPhotoPath)
-		ifErrorExit(err)
-		resp, err := c.Utility().Asm(req)
-		ifErrorExit(err)
-		writeOutput(resp)
-	},
+func newUtilityLivenessCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "liveness",
+		Short: "Run liveness check",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if utilityLivenessPhoto1Path == "" {
+				return usageErrorf("photo1 is required, supply --photo1 /path/to/image")
+			}
+			if utilityLivenessPhoto2Path == "" {
+				return usageErrorf("photo2 is required, supply --photo2 /path/to/image")
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			req, err := utility.NewLivenessRequest(utilityLivenessPhoto1Path, utilityLivenessPhoto2Path)
+			if err != nil {
+				return fmt.Errorf("read photos: %w", err)
+			}
+			resp, err := c.Utility().Liveness(req)
+			if err != nil {
+				return fmt.Errorf("liveness check: %w", err)
+			}
+			return writeOutput(resp)
+		},
+	}
 }
 
-var utilityLivenessCmd = &cobra.Command{
-	Use:   "liveness",
-	Short: "Run liveness check",
-	Run: func(cmd *cobra.Command, args []string) {
-		if utilityLivenessPhoto1Path == "" {
-			printAndExit("photo1 is required, supply --photo1 /path/to/image")
-		}
-		if utilityLivenessPhoto2Path == "" {
-			printAndExit("photo2 is required, supply --photo2 /path/to/image")
-		}
-		c := resolveUtilityClient(false)
-		req, err := utility.NewLivenessRequest(utilityLivenessPhoto1Path, utilityLivenessPhoto2Path)
-		ifErrorExit(err)
-		resp, err := c.Utility().Liveness(req)
-		ifErrorExit(err)
-		writeOutput(resp)
-	},
+func newUtilityCompareCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "compare",
+		Short: "Compare two faces (access token only)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if utilityComparePhoto1 == "" {
+				return usageErrorf("photo1 is required, supply --photo1 /path/to/image")
+			}
+			if utilityComparePhoto2 == "" {
+				return usageErrorf("photo2 is required, supply --photo2 /path/to/image")
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+
+			var confPtr *conf.Conf
+			if cmd.Flag("conf").Changed {
+				parsedConf, err := resolveConf(utilityCompareConfValue)
+				if err != nil {
+					return err
+				}
+				confPtr = &parsedConf
+			}
+
+			req, err := utility.NewCompareRequest(utilityComparePhoto1, utilityComparePhoto2, confPtr)
+			if err != nil {
+				return fmt.Errorf("read photos: %w", err)
+			}
+			req.LivenessPhoto1 = utilityCompareLivenessOne
+			req.LivenessPhoto2 = utilityCompareLivenessTwo
+
+			resp, err := c.Utility().Compare(req)
+			if err != nil {
+				return fmt.Errorf("compare faces: %w", err)
+			}
+			return writeOutput(resp)
+		},
+	}
 }
 
-var utilityCompareCmd = &cobra.Command{
-	Use:   "compare",
-	Short: "Compare two faces (access token only)",
-	Run: func(cmd *cobra.Command, args []string) {
-		if utilityComparePhoto1 == "" {
-			printAndExit("photo1 is required, supply --photo1 /path/to/image")
-		}
-		if utilityComparePhoto2 == "" {
-			printAndExit("photo2 is required, supply --photo2 /path/to/image")
-		}
-		c := resolveUtilityClient(true)
-
-		var confPtr *conf.Conf
-		if cmd.Flag("conf").Changed {
-			parsedConf, err := resolveConf(utilityCompareConfValue)
-			ifErrorExit(err)
-			confPtr = &parsedConf
-		}
-
-		req, err := utility.NewCompareRequest(utilityComparePhoto1, utilityComparePhoto2, confPtr)
-		ifErrorExit(err)
-		req.LivenessPhoto1 = utilityCompareLivenessOne
-		req.LivenessPhoto2 = utilityCompareLivenessTwo
-
-		resp, err := c.Utility().Compare(req)
-		ifErrorExit(err)
-		writeOutput(resp)
-	},
-}
+func newUtilityCmd() *cobra.Command {
+	utilityCmd := &cobra.Command{
+		Use:   "utility",
+		Short: "Access utility endpoints (health, metrics, checks)",
+	}
+
+	utilityHealthCmd := newUtilityHealthCmd()
+	utilityMetricsCmd := newUtilityMetricsCmd()
+	utilityAsmCmd := newUtilityAsmCmd()
+	utilityLivenessCmd := newUtilityLivenessCmd()
+	utilityCompareCmd := newUtilityCompareCmd()
 
-func init() {
 	utilityAsmCmd.Flags().StringVar(&utilityAsmPhotoPath, "photo", "", "path to photo")
 	utilityLivenessCmd.Flags().StringVar(&utilityLivenessPhoto1Path, "photo1", "", "path to first photo")
 	utilityLivenessCmd.Flags().StringVar(&utilityLivenessPhoto2Path, "photo2", "", "path to second photo")
@@ -127,18 +174,5 @@ func init() {
 	utilityCompareCmd.Flags().BoolVar(&utilityCompareLivenessTwo, "liveness-photo2", false, "mark second photo as liveness frame")
 
 	utilityCmd.AddCommand(utilityHealthCmd, utilityMetricsCmd, utilityAsmCmd, utilityLivenessCmd, utilityCompareCmd)
-	rootCmd.AddCommand(utilityCmd)
-}
-
-func resolveUtilityClient(requireAccess bool) *client.Client {
-	if requireAccess {
-		access := strings.TrimSpace(os.Getenv("SERP_ACCESS_TOKEN"))
-		if access == "" {
-			printAndExit("SERP_ACCESS_TOKEN is required for this command")
-		}
-		return client.NewClientWithToken(access)
-	}
-	c, err := client.NewClient()
-	ifErrorExit(err)
-	return c
+	return utilityCmd
 }
@@ -1,24 +1,29 @@
 package cmd
 
 import (
-	"github.com/serptech/serp-go/api/client"
+	"fmt"
+
 	"github.com/spf13/cobra"
 )
 
-var versionCmd = &cobra.Command{
-	Use:   "version",
-	Short: "Display API version information",
-	Run: func(cmd *cobra.Command, args []string) {
-		c, err := client.NewClient()
-		ifErrorExit(err)
+func newVersionCmd() *cobra.Command {
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Display API version information",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
 
-		resp, err := c.Users().Version()
-		ifErrorExit(err)
+			resp, err := c.Users().Version()
+			if err != nil {
+				return fmt.Errorf("get version: %w", err)
+			}
 
-		writeOutput(resp)
-	},
-}
+			return writeOutput(resp)
+		},
+	}
 
-func init() {
-	rootCmd.AddCommand(versionCmd)
+	return versionCmd
 }
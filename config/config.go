@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cliutils "github.com/serptech/serp-cli/utils"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	dirName  = "serptech"
+	fileName = "config.yaml"
+)
+
+// Context describes a single named connection: which API to talk to and
+// which credentials to use there.
+//
+// With a CredentialHelper the tokens are fetched from that program at
+// runtime instead of being kept in the file.
+type Context struct {
+	BaseURL          string `yaml:"base_url,omitempty"`
+	AccessToken      string `yaml:"access_token,omitempty"`
+	RootToken        string `yaml:"root_token,omitempty"`
+	CredentialHelper string `yaml:"credential_helper,omitempty"`
+}
+
+// Config is the on-disk CLI configuration.
+type Config struct {
+	CurrentContext string              `yaml:"current_context,omitempty"`
+	Contexts       map[string]*Context `yaml:"contexts,omitempty"`
+
+	path string
+}
+
+// DefaultPath returns the location of the config file. SERP_CONFIG wins,
+// then $XDG_CONFIG_HOME, then the platform user config directory.
+func DefaultPath() (string, error) {
+	if p := strings.TrimSpace(os.Getenv("SERP_CONFIG")); p != "" {
+		return p, nil
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Dir returns the serptech directory inside the user config directory.
+func Dir() (string, error) {
+	base := strings.TrimSpace(os.Getenv("XDG_CONFIG_HOME"))
+	if base == "" {
+		var err error
+		base, err = os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("unable to locate config directory: %w", err)
+		}
+	}
+	return filepath.Join(base, dirName), nil
+}
+
+// Load reads the config file at path. A missing file yields an empty config
+// that will be created on the first Save.
+func Load(path string) (*Config, error) {
+	cfg := &Config{Contexts: map[string]*Context{}, path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if cfg.Contexts == nil {
+		cfg.Contexts = map[string]*Context{}
+	}
+	return cfg, nil
+}
+
+// LoadDefault loads the config from DefaultPath.
+func LoadDefault() (*Config, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return Load(path)
+}
+
+// Path returns the file the config was loaded from.
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Save writes the config back to its file. The file holds tokens, so it is
+// replaced by a new one readable by the owner only, whatever the mode of the
+// old one; a failed Save leaves the old file intact.
+func (c *Config) Save() error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return cliutils.WriteFileAtomic(c.path, data)
+}
+
+// Context returns the named context.
+func (c *Config) Context(name string) (*Context, error) {
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		return nil, fmt.Errorf("context %q not found in %s", name, c.path)
+	}
+	return ctx, nil
+}
+
+// Names returns all context names in sorted order.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetContext creates or replaces the named context.
+func (c *Config) SetContext(name string, ctx *Context) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("context name is required")
+	}
+	c.Contexts[name] = ctx
+	return nil
+}
+
+// UseContext makes the named context current.
+func (c *Config) UseContext(name string) error {
+	if _, err := c.Context(name); err != nil {
+		return err
+	}
+	c.CurrentContext = name
+	return nil
+}
+
+// DeleteContext removes the named context, clearing the current context if
+// it pointed at it.
+func (c *Config) DeleteContext(name string) error {
+	if _, err := c.Context(name); err != nil {
+		return err
+	}
+	delete(c.Contexts, name)
+	if c.CurrentContext == name {
+		c.CurrentContext = ""
+	}
+	return nil
+}
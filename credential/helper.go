@@ -0,0 +1,166 @@
+// Package credential keeps tokens out of the environment and the config
+// file: it runs external credential helpers, so that tokens can live in a
+// secret manager, and stores logins in a passphrase-encrypted vault.
+//
+// The protocol follows git's credential helpers. The helper is run with one
+// of the actions get, store or erase, and reads key=value lines ending with
+// a blank line on stdin. For get it prints the credentials it knows as
+// key=value lines on stdout. The keys are:
+//
+//	context       name of the serptech context being resolved
+//	base_url      API base URL of the context, if any
+//	host          host of base_url
+//	access_token  SERP access token
+//	root_token    SERP root token
+//
+// The tokens are only sent to the helper with store. Unknown keys are
+// ignored in both directions.
+package credential
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Helper actions.
+const (
+	Get   = "get"
+	Store = "store"
+	Erase = "erase"
+)
+
+// CommandPrefix is prepended to helper names that are not found on PATH as
+// given, so "vault" may refer to a serptech-credential-vault program.
+const CommandPrefix = "serptech-credential-"
+
+// Request identifies the credentials a helper is asked about.
+type Request struct {
+	Context string
+	BaseURL string
+}
+
+// Credentials are the tokens a helper returns or is asked to store.
+type Credentials struct {
+	AccessToken string
+	RootToken   string
+}
+
+// Helper is an external credential helper. Command is either a program with
+// optional arguments or, after a leading "!", a shell command.
+type Helper struct {
+	Command string
+}
+
+// Get asks the helper for the credentials of req. Credentials the helper
+// does not know are left empty.
+func (h Helper) Get(ctx context.Context, req Request) (Credentials, error) {
+	out, err := h.run(ctx, Get, attributes(req, Credentials{}))
+	if err != nil {
+		return Credentials{}, err
+	}
+	values := parse(out)
+	return Credentials{AccessToken: values["access_token"], RootToken: values["root_token"]}, nil
+}
+
+// Store hands creds for req to the helper to keep.
+func (h Helper) Store(ctx context.Context, req Request, creds Credentials) error {
+	_, err := h.run(ctx, Store, attributes(req, creds))
+	return err
+}
+
+// Erase asks the helper to forget the credentials of req.
+func (h Helper) Erase(ctx context.Context, req Request) error {
+	_, err := h.run(ctx, Erase, attributes(req, Credentials{}))
+	return err
+}
+
+func (h Helper) run(ctx context.Context, action string, input map[string]string) ([]byte, error) {
+	cmd, err := h.command(ctx, action)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = strings.NewReader(format(input))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("credential helper %q %s: %w", h.Command, action, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func (h Helper) command(ctx context.Context, action string) (*exec.Cmd, error) {
+	command := strings.TrimSpace(h.Command)
+	if shell, ok := strings.CutPrefix(command, "!"); ok {
+		return exec.CommandContext(ctx, "sh", "-c", shell+" "+action), nil
+	}
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, errors.New("credential helper is empty")
+	}
+	name := fields[0]
+	if _, err := exec.LookPath(name); err != nil && !strings.ContainsAny(name, `/\`) {
+		if prefixed, prefixedErr := exec.LookPath(CommandPrefix + name); prefixedErr == nil {
+			name = prefixed
+		}
+	}
+	args := append(fields[1:len(fields):len(fields)], action)
+	return exec.CommandContext(ctx, name, args...), nil
+}
+
+func attributes(req Request, creds Credentials) map[string]string {
+	values := map[string]string{
+		"context":      req.Context,
+		"base_url":     req.BaseURL,
+		"access_token": creds.AccessToken,
+		"root_token":   creds.RootToken,
+	}
+	if u, err := url.Parse(req.BaseURL); err == nil {
+		values["host"] = u.Host
+	}
+	return values
+}
+
+// format renders the non-empty values as sorted key=value lines followed by
+// a blank line.
+func format(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key, value := range values {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", key, values[key])
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// parse reads key=value lines up to the first blank line.
+func parse(data []byte) map[string]string {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			break
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			values[strings.TrimSpace(key)] = value
+		}
+	}
+	return values
+}
@@ -0,0 +1,44 @@
+package credential
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHelperProtocol(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input")
+	// The helper records what it is sent and answers get with fixed tokens.
+	h := Helper{Command: "!f() { cat > " + input + "; [ \"$1\" = get ] && printf 'access_token=acc\\nroot_token=root\\n\\nignored=1\\n'; true; }; f"}
+	req := Request{Context: "prod", BaseURL: "https://api.example.com/v1"}
+
+	creds, err := h.Get(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds != (Credentials{AccessToken: "acc", RootToken: "root"}) {
+		t.Errorf("Get = %+v", creds)
+	}
+	want := "base_url=https://api.example.com/v1\ncontext=prod\nhost=api.example.com\n\n"
+	if got, _ := os.ReadFile(input); string(got) != want {
+		t.Errorf("get input = %q, want %q", got, want)
+	}
+
+	if err := h.Store(context.Background(), req, Credentials{AccessToken: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(input); !strings.Contains(string(got), "access_token=new\n") {
+		t.Errorf("store input = %q", got)
+	}
+}
+
+func TestHelperFailure(t *testing.T) {
+	h := Helper{Command: "!echo vault is sealed >&2; exit 1; :"}
+	_, err := h.Get(context.Background(), Request{Context: "prod"})
+	if err == nil || !strings.Contains(err.Error(), "vault is sealed") {
+		t.Errorf("got %v, want the helper's stderr in the error", err)
+	}
+}
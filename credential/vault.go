@@ -0,0 +1,245 @@
+package credential
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	cliutils "github.com/serptech/serp-cli/utils"
+	"golang.org/x/crypto/scrypt"
+)
+
+// VaultFile is the name of the encrypted credential file kept next to the
+// config file.
+const VaultFile = "credentials.enc"
+
+// ErrPassphrase is returned when a vault cannot be decrypted with the
+// passphrase given.
+var ErrPassphrase = errors.New("wrong passphrase or corrupted credential file")
+
+// scrypt parameters recommended for interactive logins.
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLen      = 16
+)
+
+// Limits on the scrypt cost read from a vault, so that a tampered file
+// cannot make opening it take unbounded memory or time. scrypt needs
+// 128 * N * R bytes.
+const (
+	maxScryptN      = 1 << 20
+	maxScryptP      = 16
+	maxScryptMemory = 1 << 30
+)
+
+// Login is a token saved with serptech login.
+type Login struct {
+	BaseURL  string    `json:"base_url,omitempty"`
+	Token    string    `json:"token"`
+	Root     bool      `json:"root,omitempty"`
+	Identity string    `json:"identity,omitempty"`
+	SavedAt  time.Time `json:"saved_at"`
+}
+
+// Vault is a passphrase-encrypted file of logins keyed by context name.
+// The context names are stored in the clear so that callers can tell
+// whether a vault has anything for them before asking for the passphrase.
+type Vault struct {
+	path       string
+	passphrase []byte
+	logins     map[string][]Login
+}
+
+// envelope is the on-disk form of a vault.
+type envelope struct {
+	Version    int      `json:"version"`
+	KDF        string   `json:"kdf"`
+	N          int      `json:"n"`
+	R          int      `json:"r"`
+	P          int      `json:"p"`
+	Salt       []byte   `json:"salt"`
+	Nonce      []byte   `json:"nonce"`
+	Contexts   []string `json:"contexts"`
+	Ciphertext []byte   `json:"ciphertext"`
+}
+
+// additionalData returns what the vault's AEAD authenticates besides the
+// ciphertext: every header field, so none can be changed unnoticed.
+func (env *envelope) additionalData() []byte {
+	header := *env
+	header.Nonce, header.Ciphertext = nil, nil
+	data, _ := json.Marshal(header)
+	return data
+}
+
+// checkParams rejects scrypt parameters Save never writes and that would be
+// too costly to derive a key with.
+func (env *envelope) checkParams() error {
+	switch {
+	case env.N < 2 || env.N > maxScryptN || env.N&(env.N-1) != 0:
+		return fmt.Errorf("unsupported scrypt N %d", env.N)
+	case env.R < 1 || env.R > maxScryptMemory/128/env.N || env.P < 1 || env.P > maxScryptP:
+		return fmt.Errorf("unsupported scrypt parameters r=%d p=%d", env.R, env.P)
+	case len(env.Salt) < saltLen:
+		return fmt.Errorf("salt of %d bytes is too short", len(env.Salt))
+	}
+	return nil
+}
+
+// VaultContexts returns the context names a vault holds logins for without
+// decrypting it. A missing file holds none.
+func VaultContexts(path string) ([]string, error) {
+	env, err := readEnvelope(path)
+	if err != nil || env == nil {
+		return nil, err
+	}
+	return env.Contexts, nil
+}
+
+// OpenVault decrypts the vault at path. A missing file yields an empty vault
+// that Save creates, encrypted with passphrase.
+func OpenVault(path string, passphrase []byte) (*Vault, error) {
+	v := &Vault{path: path, passphrase: passphrase, logins: map[string][]Login{}}
+	env, err := readEnvelope(path)
+	if err != nil || env == nil {
+		return v, err
+	}
+	if env.Version != 1 || env.KDF != "scrypt" {
+		return nil, fmt.Errorf("%s: unsupported credential file version %d (%s)", path, env.Version, env.KDF)
+	}
+	if err := env.checkParams(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	aead, err := newAEAD(passphrase, env.Salt, env.N, env.R, env.P)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != aead.NonceSize() {
+		return nil, ErrPassphrase
+	}
+	plain, err := aead.Open(nil, env.Nonce, env.Ciphertext, env.additionalData())
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	if err := json.Unmarshal(plain, &v.logins); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return v, nil
+}
+
+func readEnvelope(path string) (*envelope, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &env, nil
+}
+
+func newAEAD(passphrase, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, n, r, p, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Path returns the file the vault is stored in.
+func (v *Vault) Path() string {
+	return v.path
+}
+
+// Logins returns the logins saved for the named context, the access token
+// before the root token.
+func (v *Vault) Logins(context string) []Login {
+	return v.logins[context]
+}
+
+// Contexts returns the names of the contexts with logins, sorted.
+func (v *Vault) Contexts() []string {
+	names := make([]string, 0, len(v.logins))
+	for name := range v.logins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set saves login for the named context, replacing a login of the same
+// kind.
+func (v *Vault) Set(context string, login Login) {
+	logins := []Login{login}
+	for _, l := range v.logins[context] {
+		if l.Root != login.Root {
+			logins = append(logins, l)
+		}
+	}
+	sort.SliceStable(logins, func(i, j int) bool { return !logins[i].Root && logins[j].Root })
+	v.logins[context] = logins
+}
+
+// Delete removes the logins of the named context and reports whether there
+// were any.
+func (v *Vault) Delete(context string) bool {
+	_, ok := v.logins[context]
+	delete(v.logins, context)
+	return ok
+}
+
+// Save encrypts the vault with a fresh salt and nonce and replaces the file
+// with it, readable by the owner only. The file is written under a temporary
+// name and renamed, so a failed Save leaves the previous vault intact. An
+// empty vault removes the file.
+func (v *Vault) Save() error {
+	if len(v.logins) == 0 {
+		if err := os.Remove(v.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	plain, err := json.Marshal(v.logins)
+	if err != nil {
+		return err
+	}
+	env := envelope{Version: 1, KDF: "scrypt", N: scryptN, R: scryptR, P: scryptP, Contexts: v.Contexts()}
+	env.Salt = make([]byte, saltLen)
+	if _, err := rand.Read(env.Salt); err != nil {
+		return err
+	}
+	aead, err := newAEAD(v.passphrase, env.Salt, env.N, env.R, env.P)
+	if err != nil {
+		return err
+	}
+	env.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return err
+	}
+	env.Ciphertext = aead.Seal(nil, env.Nonce, plain, env.additionalData())
+
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+	return cliutils.WriteFileAtomic(v.path, append(data, '\n'))
+}
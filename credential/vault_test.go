@@ -0,0 +1,112 @@
+package credential
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVaultRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), VaultFile)
+	v, err := OpenVault(path, []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Set("prod", Login{BaseURL: "https://api.example.com", Token: "root-secret", Root: true})
+	v.Set("prod", Login{Token: "access-secret"})
+	v.Set("prod", Login{Token: "access-secret-2"})
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Fatalf("tokens stored in the clear:\n%s", data)
+	}
+	if names, err := VaultContexts(path); err != nil || len(names) != 1 || names[0] != "prod" {
+		t.Errorf("VaultContexts = %v, %v", names, err)
+	}
+
+	if _, err := OpenVault(path, []byte("wrong")); !errors.Is(err, ErrPassphrase) {
+		t.Errorf("wrong passphrase: got %v", err)
+	}
+	v, err = OpenVault(path, []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logins := v.Logins("prod")
+	if len(logins) != 2 || logins[0].Token != "access-secret-2" || !logins[1].Root {
+		t.Errorf("Logins = %+v", logins)
+	}
+
+	v.Delete("prod")
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("empty vault left %s behind", path)
+	}
+}
+
+func TestVaultHeaderIsAuthenticated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), VaultFile)
+	v, err := OpenVault(path, []byte("pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Set("prod", Login{Token: "access-secret"})
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("vault mode = %v, %v", info.Mode(), err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Save left %d files behind", len(entries))
+	}
+
+	tamper := func(t *testing.T, edit func(env *envelope)) error {
+		t.Helper()
+		env, err := readEnvelope(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		edit(env)
+		data, err := json.Marshal(env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tampered := filepath.Join(t.TempDir(), VaultFile)
+		if err := os.WriteFile(tampered, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err = OpenVault(tampered, []byte("pw"))
+		return err
+	}
+	tests := []struct {
+		name string
+		edit func(env *envelope)
+		want string
+	}{
+		{"contexts", func(env *envelope) { env.Contexts = []string{"prod", "staging"} }, ErrPassphrase.Error()},
+		{"salt", func(env *envelope) { env.Salt[0] ^= 1 }, ErrPassphrase.Error()},
+		{"short nonce", func(env *envelope) { env.Nonce = env.Nonce[:4] }, ErrPassphrase.Error()},
+		{"huge n", func(env *envelope) { env.N = 1 << 30 }, "unsupported scrypt N"},
+		{"odd n", func(env *envelope) { env.N = 3 << 10 }, "unsupported scrypt N"},
+		{"huge r", func(env *envelope) { env.R = 1 << 40 }, "unsupported scrypt parameters"},
+		{"huge p", func(env *envelope) { env.P = 1 << 20 }, "unsupported scrypt parameters"},
+		{"short salt", func(env *envelope) { env.Salt = env.Salt[:2] }, "too short"},
+		{"version", func(env *envelope) { env.Version = 2 }, "unsupported credential file version"},
+	}
+	for _, tc := range tests {
+		if err := tamper(t, tc.edit); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: OpenVault error = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package dotenv reads .env files: KEY=VALUE lines as understood by shells
+// and docker compose.
+//
+// Blank lines and lines starting with # are skipped and a leading "export "
+// is ignored. Values may be single-quoted, taken literally, or double-quoted,
+// where \n, \t, \", \\ and \$ are unescaped. Unquoted values end at a # that
+// follows whitespace. $NAME and ${NAME} are expanded in unquoted and
+// double-quoted values, from the variables defined above them in the file
+// first and then through the lookup function.
+package dotenv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Var is one variable defined by a file.
+type Var struct {
+	Key   string
+	Value string
+}
+
+// Read parses the file at path.
+func Read(path string, lookup func(string) (string, bool)) ([]Var, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	vars, err := Parse(f, lookup)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+	return vars, nil
+}
+
+// Parse reads variables from r in the order they are defined. Errors start
+// with the line number. lookup may be nil.
+func Parse(r io.Reader, lookup func(string) (string, bool)) ([]Var, error) {
+	var vars []Var
+	defined := map[string]string{}
+	expand := func(name string) string {
+		if value, ok := defined[name]; ok {
+			return value
+		}
+		if lookup != nil {
+			value, _ := lookup(name)
+			return value
+		}
+		return ""
+	}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "export "); ok {
+			line = strings.TrimSpace(rest)
+		}
+		key, raw, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok {
+			return nil, fmt.Errorf("%d: expected KEY=VALUE", n)
+		}
+		if !validKey(key) {
+			return nil, fmt.Errorf("%d: invalid variable name %q", n, key)
+		}
+		value, err := parseValue(strings.TrimSpace(raw), expand)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %s: %w", n, key, err)
+		}
+		defined[key] = value
+		vars = append(vars, Var{Key: key, Value: value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+func parseValue(raw string, expand func(string) string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+	var b strings.Builder
+	var rest string
+	switch quote := raw[0]; quote {
+	case '\'':
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single quote")
+		}
+		b.WriteString(raw[1 : end+1])
+		rest = raw[end+2:]
+	case '"':
+		i := 1
+		for ; i < len(raw) && raw[i] != '"'; i++ {
+			switch c := raw[i]; {
+			case c == '\\' && i+1 < len(raw):
+				i++
+				switch raw[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				case 'r':
+					b.WriteByte('\r')
+				case '"', '\\', '$':
+					b.WriteByte(raw[i])
+				default:
+					b.WriteByte('\\')
+					b.WriteByte(raw[i])
+				}
+			case c == '$':
+				i += expandAt(&b, raw[i:], expand) - 1
+			default:
+				b.WriteByte(c)
+			}
+		}
+		if i >= len(raw) {
+			return "", fmt.Errorf("unterminated double quote")
+		}
+		rest = raw[i+1:]
+	default:
+		if i := strings.Index(raw, " #"); i >= 0 {
+			raw = raw[:i]
+		}
+		if i := strings.Index(raw, "\t#"); i >= 0 {
+			raw = raw[:i]
+		}
+		raw = strings.TrimSpace(raw)
+		for i := 0; i < len(raw); i++ {
+			if raw[i] == '$' {
+				i += expandAt(&b, raw[i:], expand) - 1
+				continue
+			}
+			b.WriteByte(raw[i])
+		}
+		return b.String(), nil
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %q after the closing quote", rest)
+	}
+	return b.String(), nil
+}
+
+// expandAt writes the expansion of the reference at the start of s, which
+// begins with $, and returns how many bytes it took. A $ that starts no
+// reference is kept.
+func expandAt(b *strings.Builder, s string, expand func(string) string) int {
+	if strings.HasPrefix(s, "${") {
+		if end := strings.IndexByte(s, '}'); end > 2 && validKey(s[2:end]) {
+			b.WriteString(expand(s[2:end]))
+			return end + 1
+		}
+	}
+	n := 1
+	for n < len(s) && isNameByte(s[n], n == 1) {
+		n++
+	}
+	if n == 1 {
+		b.WriteByte('$')
+		return 1
+	}
+	b.WriteString(expand(s[1:n]))
+	return n
+}
+
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if !isNameByte(key[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameByte(c byte, first bool) bool {
+	switch {
+	case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		return true
+	case '0' <= c && c <= '9':
+		return !first
+	}
+	return false
+}
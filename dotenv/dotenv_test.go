@@ -0,0 +1,58 @@
+package dotenv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	input := `# serptech
+export SERP_BASE_URL=https://api.serptech.ru   # staging
+SERP_ACCESS_TOKEN = 'lit$eral # kept'
+GREETING="hello\n$USER"
+URL=${SERP_BASE_URL}/v1
+PRICE=5$
+EMPTY=
+`
+	env := map[string]string{"USER": "ops", "SERP_BASE_URL": "ignored"}
+	vars, err := Parse(strings.NewReader(input), func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Var{
+		{"SERP_BASE_URL", "https://api.serptech.ru"},
+		{"SERP_ACCESS_TOKEN", "lit$eral # kept"},
+		{"GREETING", "hello\nops"},
+		{"URL", "https://api.serptech.ru/v1"},
+		{"PRICE", "5$"},
+		{"EMPTY", ""},
+	}
+	if len(vars) != len(want) {
+		t.Fatalf("got %d vars %q, want %d", len(vars), vars, len(want))
+	}
+	for i := range want {
+		if vars[i] != want[i] {
+			t.Errorf("var %d = %q, want %q", i, vars[i], want[i])
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"SERP_TOKEN", "1: expected KEY=VALUE"},
+		{"\n1X=2", "2: invalid variable name \"1X\""},
+		{`A="open`, "1: A: unterminated double quote"},
+		{`A='x' y`, "1: A: unexpected \"y\" after the closing quote"},
+	}
+	for _, tc := range tests {
+		_, err := Parse(strings.NewReader(tc.in), nil)
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("Parse(%q) error = %v, want %q", tc.in, err, tc.want)
+		}
+	}
+}
@@ -3,13 +3,19 @@ module github.com/serptech/serp-cli
 go 1.24.0
 
 require (
+	github.com/PaesslerAG/jsonpath v0.1.1
+	github.com/jmespath/go-jmespath v0.4.0
 	github.com/rs/zerolog v1.34.0
 	github.com/serptech/serp-go v0.3.0
 	github.com/spf13/cobra v1.10.1
 	github.com/tidwall/pretty v1.2.1
+	golang.org/x/crypto v0.40.0
+	golang.org/x/term v0.33.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/PaesslerAG/gval v1.0.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
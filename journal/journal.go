@@ -0,0 +1,173 @@
+// Package journal records the outcome of every item of a bulk job in an
+// append-only JSON lines file, so that an interrupted or partially failed
+// run can be resumed and only the unfinished items retried.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a journaled item.
+type Status string
+
+const (
+	Done   Status = "done"
+	Failed Status = "failed"
+)
+
+// Record is one line of the journal.
+type Record struct {
+	Key    string            `json:"key"`
+	Status Status            `json:"status"`
+	Result map[string]string `json:"result,omitempty"`
+	Error  string            `json:"error,omitempty"`
+	Time   time.Time         `json:"time"`
+}
+
+type header struct {
+	Job     string    `json:"job"`
+	Created time.Time `json:"created"`
+}
+
+// Journal is safe for concurrent use by the workers of a bulk job.
+type Journal struct {
+	mu      sync.Mutex
+	path    string
+	f       *os.File
+	w       *bufio.Writer
+	records map[string]Record
+}
+
+// Create starts a new journal for job at path. It refuses to replace an
+// existing journal, whose records a resumed run would need; the error then
+// matches fs.ErrExist.
+func Create(path, job string) (*Journal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{path: path, f: f, w: bufio.NewWriter(f), records: map[string]Record{}}
+	if err := j.writeLine(header{Job: job, Created: time.Now().UTC()}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// Resume reopens an existing journal for job. Its records are loaded so that
+// Done reports items finished by earlier runs, and new outcomes are
+// appended. Resuming a journal written by a different job is an error.
+func Resume(path, job string) (*Journal, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resume: %w", err)
+	}
+	j := &Journal{path: path, records: map[string]Record{}}
+	if err := j.load(existing, job); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	// Drop a last line cut short by a crash, so the next record starts on a
+	// line of its own instead of merging into it.
+	if complete := int64(bytes.LastIndexByte(existing, '\n') + 1); complete < int64(len(existing)) {
+		if err := f.Truncate(complete); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	j.f = f
+	j.w = bufio.NewWriter(f)
+	return j, nil
+}
+
+func (j *Journal) load(data []byte, job string) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	line, badLine := 0, 0
+	var badErr error
+	for scanner.Scan() {
+		line++
+		raw := scanner.Bytes()
+		if len(raw) == 0 {
+			continue
+		}
+		// Only the last line may be cut short by a crash; a bad record with
+		// more records after it means the journal is damaged.
+		if badErr != nil {
+			return fmt.Errorf("journal %s: invalid record on line %d: %w", j.path, badLine, badErr)
+		}
+		if line == 1 {
+			var h header
+			if err := json.Unmarshal(raw, &h); err != nil {
+				return fmt.Errorf("journal %s: invalid header: %w", j.path, err)
+			}
+			if h.Job != job {
+				return fmt.Errorf("journal %s belongs to job %q, not %q", j.path, h.Job, job)
+			}
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			badLine, badErr = line, err
+			continue
+		}
+		j.records[rec.Key] = rec
+	}
+	return scanner.Err()
+}
+
+// Path returns the journal file location.
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Done returns the record of an item that already completed successfully.
+func (j *Journal) Done(key string) (Record, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	rec, ok := j.records[key]
+	return rec, ok && rec.Status == Done
+}
+
+// Record appends the outcome of an item and flushes it to disk.
+func (j *Journal) Record(rec Record) error {
+	if rec.Time.IsZero() {
+		rec.Time = time.Now().UTC()
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.records[rec.Key] = rec
+	return j.writeLine(rec)
+}
+
+// Close flushes and closes the journal file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.w.Flush(); err != nil {
+		j.f.Close()
+		return err
+	}
+	return j.f.Close()
+}
+
+func (j *Journal) writeLine(v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := j.w.Write(append(raw, '\n')); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
@@ -0,0 +1,146 @@
+package journal
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import.journal")
+	j, err := Create(path, "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rec := range []Record{
+		{Key: "a.jpg", Status: Done, Result: map[string]string{"id": "1"}},
+		{Key: "b.jpg", Status: Failed, Error: "no face"},
+	} {
+		if err := j.Record(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err = Resume(path, "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if rec, ok := j.Done("a.jpg"); !ok || rec.Result["id"] != "1" {
+		t.Errorf("Done(a.jpg) = %+v, %v", rec, ok)
+	}
+	if _, ok := j.Done("b.jpg"); ok {
+		t.Error("failed item b.jpg reported done")
+	}
+	if _, ok := j.Done("c.jpg"); ok {
+		t.Error("unknown item c.jpg reported done")
+	}
+}
+
+func TestResumeOtherJob(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import.journal")
+	j, err := Create(path, "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+	if _, err := Resume(path, "export"); err == nil || !strings.Contains(err.Error(), `belongs to job "import"`) {
+		t.Errorf("Resume with another job: %v", err)
+	}
+	if _, err := Resume(filepath.Join(t.TempDir(), "missing"), "import"); err == nil {
+		t.Error("Resume of a missing journal succeeded")
+	}
+}
+
+func TestCreateKeepsExistingJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import.journal")
+	j, err := Create(path, "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Record(Record{Key: "a.jpg", Status: Done}); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	if _, err := Create(path, "import"); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Create over an existing journal: %v", err)
+	}
+	j, err = Resume(path, "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if _, ok := j.Done("a.jpg"); !ok {
+		t.Error("a.jpg is no longer done")
+	}
+}
+
+func TestResumeDamagedJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import.journal")
+	j, err := Create(path, "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{\"key\":\"a.jpg\",\"sta\n{\"key\":\"b.jpg\",\"status\":\"done\"}\n")
+	f.Close()
+
+	if _, err := Resume(path, "import"); err == nil || !strings.Contains(err.Error(), "invalid record on line 2") {
+		t.Errorf("Resume of a damaged journal: %v", err)
+	}
+}
+
+func TestResumeAfterTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "import.journal")
+	j, err := Create(path, "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Record(Record{Key: "a.jpg", Status: Done}); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	// A crash cut the record of b.jpg short.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"key":"b.jpg","sta`)
+	f.Close()
+
+	j, err = Resume(path, "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Record(Record{Key: "c.jpg", Status: Done}); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	j, err = Resume(path, "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	for _, key := range []string{"a.jpg", "c.jpg"} {
+		if _, ok := j.Done(key); !ok {
+			t.Errorf("%s is not done after resuming twice", key)
+		}
+	}
+	if _, ok := j.Done("b.jpg"); ok {
+		t.Error("truncated b.jpg reported done")
+	}
+}
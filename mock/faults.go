@@ -0,0 +1,140 @@
+package mock
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault describes a failure injected into matching requests. Matching is
+// counted per rule, so a given sequence of requests always fails the same way.
+type Fault struct {
+	// Method and Path select requests; Path is a prefix such as "/v1/entries/".
+	// Empty values match everything.
+	Method string `yaml:"method"`
+	Path   string `yaml:"path"`
+
+	// After skips the first N matching requests, Times limits the rule to N
+	// requests after that (0 means no limit) and Every applies it to every Nth
+	// remaining request only.
+	After int `yaml:"after"`
+	Times int `yaml:"times"`
+	Every int `yaml:"every"`
+
+	// Latency delays the response.
+	Latency time.Duration `yaml:"latency"`
+	// Status replaces the response with an error, e.g. 429 or 503.
+	Status int `yaml:"status"`
+	// RetryAfter sets the Retry-After header, in seconds, on injected errors.
+	RetryAfter int `yaml:"retry_after"`
+	// Truncate cuts the real response body in half while still announcing
+	// the full Content-Length.
+	Truncate bool `yaml:"truncate"`
+}
+
+func (f Fault) validate() error {
+	if f.Status != 0 && (f.Status < 400 || f.Status > 599) {
+		return fmt.Errorf("status %d is not an error status", f.Status)
+	}
+	if f.Status != 0 && f.Truncate {
+		return errors.New("status and truncate are mutually exclusive")
+	}
+	if f.After < 0 || f.Times < 0 || f.Every < 0 || f.Latency < 0 {
+		return errors.New("after, times, every and latency must not be negative")
+	}
+	return nil
+}
+
+func (f Fault) matches(r *http.Request) bool {
+	if f.Method != "" && !strings.EqualFold(f.Method, r.Method) {
+		return false
+	}
+	return strings.HasPrefix(r.URL.Path, f.Path)
+}
+
+// faultSet tracks how many requests each rule has seen.
+type faultSet struct {
+	mu     sync.Mutex
+	rules  []Fault
+	counts []int
+}
+
+func newFaultSet(rules []Fault) *faultSet {
+	return &faultSet{rules: rules, counts: make([]int, len(rules))}
+}
+
+// pick returns the faults that apply to the request, advancing the counters
+// of every matching rule.
+func (fs *faultSet) pick(r *http.Request) []Fault {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	var active []Fault
+	for i, rule := range fs.rules {
+		if !rule.matches(r) {
+			continue
+		}
+		fs.counts[i]++
+		n := fs.counts[i] - rule.After
+		if n <= 0 || rule.Times > 0 && n > rule.Times || rule.Every > 1 && n%rule.Every != 0 {
+			continue
+		}
+		active = append(active, rule)
+	}
+	return active
+}
+
+// inject applies faults around the next handler.
+func (fs *faultSet) inject(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		faults := fs.pick(r)
+		truncate := false
+		for _, f := range faults {
+			if f.Latency > 0 {
+				select {
+				case <-time.After(f.Latency):
+				case <-r.Context().Done():
+					return
+				}
+			}
+			if f.Status != 0 {
+				if f.RetryAfter > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(f.RetryAfter))
+				}
+				writeError(w, f.Status, fmt.Sprintf("injected fault: %s", http.StatusText(f.Status)))
+				return
+			}
+			truncate = truncate || f.Truncate
+		}
+		if !truncate {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		buf := &bufferedResponse{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(buf, r)
+		for key, values := range buf.header {
+			w.Header()[key] = values
+		}
+		body := buf.body.Bytes()
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(buf.status)
+		_, _ = w.Write(body[:len(body)/2])
+	})
+}
+
+// bufferedResponse captures a handler's response so it can be rewritten.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header         { return b.header }
+func (b *bufferedResponse) Write(p []byte) (int, error) { return b.body.Write(p) }
+func (b *bufferedResponse) WriteHeader(status int)      { b.status = status }
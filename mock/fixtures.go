@@ -0,0 +1,187 @@
+package mock
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/serptech/serp-go/api/const/conf"
+	"github.com/serptech/serp-go/api/const/liveness"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures seeds the mock server state and configures fault injection.
+// Files may be written in YAML or JSON.
+type Fixtures struct {
+	Origins  []Origin         `yaml:"origins"`
+	Profiles []ProfileFixture `yaml:"profiles"`
+	Entries  []Entry          `yaml:"entries"`
+	Users    []User           `yaml:"users"`
+	Tokens   []Token          `yaml:"tokens"`
+	Generate *Generate        `yaml:"generate"`
+	Faults   []Fault          `yaml:"faults"`
+}
+
+// ProfileFixture describes an enrolled profile. When Photo is set, searching
+// with the same file matches the profile.
+type ProfileFixture struct {
+	ID       string    `yaml:"id"`
+	OriginID int       `yaml:"origin_id"`
+	Photo    string    `yaml:"photo"`
+	Created  time.Time `yaml:"created"`
+}
+
+// Generate produces synthetic profiles and entries from a fixed seed so the
+// same fixtures always yield the same data.
+type Generate struct {
+	Seed     int64     `yaml:"seed"`
+	Profiles int       `yaml:"profiles"`
+	Entries  int       `yaml:"entries"`
+	DateFrom time.Time `yaml:"date_from"`
+	DateTo   time.Time `yaml:"date_to"`
+}
+
+// LoadFixtures reads a fixtures file. Relative profile photo paths are
+// resolved against the file's directory.
+func LoadFixtures(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixtures
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i := range f.Profiles {
+		if photo := f.Profiles[i].Photo; photo != "" && !filepath.IsAbs(photo) {
+			f.Profiles[i].Photo = filepath.Join(filepath.Dir(path), photo)
+		}
+	}
+	return &f, nil
+}
+
+// load replaces the seeded state with the fixture contents.
+func (s *store) load(f *Fixtures) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(f.Origins) > 0 {
+		s.origins = map[int]*Origin{}
+		for i := range f.Origins {
+			o := f.Origins[i]
+			if o.ID == 0 {
+				o.ID = s.nextOriginID
+			}
+			s.origins[o.ID] = &o
+			s.nextOriginID = max(s.nextOriginID, o.ID+1)
+		}
+	}
+	if len(f.Users) > 0 {
+		s.users = map[int]*User{}
+		for i := range f.Users {
+			u := f.Users[i]
+			s.users[u.ID] = &u
+		}
+	}
+	for i := range f.Tokens {
+		t := f.Tokens[i]
+		if t.Created.IsZero() {
+			t.Created = s.now()
+		}
+		s.accessTokens[t.Key] = &t
+	}
+
+	for _, pf := range f.Profiles {
+		p := &Profile{ID: pf.ID, OriginID: pf.OriginID, Conf: conf.New, Created: pf.Created}
+		if p.Created.IsZero() {
+			p.Created = s.now()
+		}
+		if pf.Photo != "" {
+			data, err := os.ReadFile(pf.Photo)
+			if err != nil {
+				return fmt.Errorf("profile %s: %w", pf.ID, err)
+			}
+			p.hash = photoHash(data)
+		}
+		if p.ID == "" {
+			if p.hash == "" {
+				return fmt.Errorf("profile fixture needs an id or a photo")
+			}
+			p.ID = profileID(p.hash)
+		}
+		s.profiles[p.ID] = p
+	}
+
+	for i := range f.Entries {
+		e := f.Entries[i]
+		if e.ID == 0 {
+			e.ID = s.nextEntryID
+		}
+		if e.Created.IsZero() {
+			e.Created = s.now()
+		}
+		s.entries[e.ID] = &e
+		s.nextEntryID = max(s.nextEntryID, e.ID+1)
+	}
+
+	if f.Generate != nil {
+		s.generate(*f.Generate)
+	}
+	return nil
+}
+
+var (
+	generatedConfs    = []conf.Conf{conf.Exact, conf.Exact, conf.Exact, conf.New, conf.Nm, conf.Ha, conf.Junk, conf.Det}
+	generatedLiveness = []liveness.Liveness{liveness.Passed, liveness.Passed, liveness.Passed, liveness.Failed, liveness.Undetermined}
+)
+
+func (s *store) generate(g Generate) {
+	rng := rand.New(rand.NewSource(g.Seed))
+	origins := s.sortedOrigins()
+	if len(origins) == 0 {
+		return
+	}
+
+	from, to := g.DateFrom, g.DateTo
+	if from.IsZero() {
+		from = s.now().AddDate(0, 0, -30)
+	}
+	if to.IsZero() || !to.After(from) {
+		to = from.AddDate(0, 0, 30)
+	}
+
+	people := make([]string, 0, g.Profiles)
+	for i := 0; i < g.Profiles; i++ {
+		hash := photoHash([]byte(fmt.Sprintf("generated-%d-%d", g.Seed, i)))
+		p := &Profile{
+			ID:       profileID(hash),
+			OriginID: origins[rng.Intn(len(origins))].ID,
+			Conf:     conf.New,
+			Created:  from,
+			hash:     hash,
+		}
+		s.profiles[p.ID] = p
+		people = append(people, p.ID)
+	}
+
+	span := to.Sub(from)
+	for i := 0; i < g.Entries; i++ {
+		c := generatedConfs[rng.Intn(len(generatedConfs))]
+		person := ""
+		if len(people) > 0 && (c == conf.Exact || c == conf.New || c == conf.Ha || c == conf.Junk) {
+			person = people[rng.Intn(len(people))]
+		}
+		e := &Entry{
+			ID:       s.nextEntryID,
+			Created:  from.Add(span * time.Duration(i) / time.Duration(g.Entries)).Truncate(time.Second),
+			OriginID: origins[rng.Intn(len(origins))].ID,
+			PersonID: person,
+			Conf:     c,
+			Liveness: generatedLiveness[rng.Intn(len(generatedLiveness))],
+		}
+		s.entries[e.ID] = e
+		s.nextEntryID++
+	}
+}
@@ -0,0 +1,637 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/serptech/serp-go/api/const/conf"
+	"github.com/serptech/serp-go/api/const/liveness"
+)
+
+const maxUploadSize = 32 << 20
+
+// Origins
+
+func (s *Server) listOrigins(w http.ResponseWriter, r *http.Request) {
+	s.store.mu.Lock()
+	items := s.store.sortedOrigins()
+	s.store.mu.Unlock()
+
+	if name := r.URL.Query().Get("q"); name != "" {
+		filtered := items[:0:0]
+		for _, o := range items {
+			if strings.Contains(strings.ToLower(o.Name), strings.ToLower(name)) {
+				filtered = append(filtered, o)
+			}
+		}
+		items = filtered
+	}
+	writeJSON(w, http.StatusOK, paginate(r, items))
+}
+
+func (s *Server) createOrigin(w http.ResponseWriter, r *http.Request) {
+	o := &Origin{IsActive: true, MinFacesize: 80, EntryStorageDays: 30}
+	if err := json.NewDecoder(r.Body).Decode(o); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(o.Name) == "" {
+		writeError(w, http.StatusBadRequest, "name: This field is required.")
+		return
+	}
+
+	s.store.mu.Lock()
+	o.ID = s.store.nextOriginID
+	s.store.nextOriginID++
+	s.store.origins[o.ID] = o
+	s.store.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, o)
+}
+
+func (s *Server) getOrigin(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	s.store.mu.Lock()
+	o, found := s.store.origins[id]
+	var copied Origin
+	if found {
+		copied = *o
+	}
+	s.store.mu.Unlock()
+	if !found {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	writeJSON(w, http.StatusOK, copied)
+}
+
+func (s *Server) updateOrigin(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	var patch struct {
+		Name              *string `json:"name"`
+		IsActive          *bool   `json:"is_active"`
+		MinFacesize       *int    `json:"min_facesize"`
+		EntryStorageDays  *int    `json:"entry_storage_days"`
+		CreateMinFacesize *int    `json:"create_min_facesize"`
+		CreateHa          *bool   `json:"create_ha"`
+		CreateJunk        *bool   `json:"create_junk"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	o, found := s.store.origins[id]
+	if !found {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	if patch.Name != nil {
+		o.Name = *patch.Name
+	}
+	if patch.IsActive != nil {
+		o.IsActive = *patch.IsActive
+	}
+	if patch.MinFacesize != nil {
+		o.MinFacesize = *patch.MinFacesize
+	}
+	if patch.EntryStorageDays != nil {
+		o.EntryStorageDays = *patch.EntryStorageDays
+	}
+	if patch.CreateMinFacesize != nil {
+		o.CreateMinFacesize = *patch.CreateMinFacesize
+	}
+	if patch.CreateHa != nil {
+		o.CreateHa = *patch.CreateHa
+	}
+	if patch.CreateJunk != nil {
+		o.CreateJunk = *patch.CreateJunk
+	}
+	writeJSON(w, http.StatusOK, o)
+}
+
+func (s *Server) deleteOrigin(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	s.store.mu.Lock()
+	_, found := s.store.origins[id]
+	delete(s.store.origins, id)
+	s.store.mu.Unlock()
+	if !found {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Profiles
+
+func (s *Server) createProfile(w http.ResponseWriter, r *http.Request) {
+	photo, ok := formPhoto(w, r, "photo")
+	if !ok {
+		return
+	}
+	originID, err := strconv.Atoi(r.FormValue("origin_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "origin_id: A valid integer is required.")
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if _, found := s.store.origins[originID]; !found {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("origin_id: Origin %d does not exist.", originID))
+		return
+	}
+
+	hash := photoHash(photo)
+	p := s.store.profileByHash(hash)
+	result := conf.Exact
+	if p == nil {
+		p = &Profile{ID: profileID(hash), OriginID: originID, Conf: conf.New, Created: s.store.now(), hash: hash}
+		s.store.profiles[p.ID] = p
+		result = conf.New
+	}
+	s.store.addEntry(originID, p.ID, result, livenessOf(hash))
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"id":        p.ID,
+		"conf":      result,
+		"origin_id": originID,
+		"created":   p.Created,
+	})
+}
+
+func (s *Server) searchProfile(w http.ResponseWriter, r *http.Request) {
+	photo, ok := formPhoto(w, r, "photo")
+	if !ok {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	p := s.store.profileByHash(photoHash(photo))
+	if p == nil {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": nil, "conf": conf.Nm})
+		return
+	}
+	s.store.addEntry(p.OriginID, p.ID, conf.Exact, livenessOf(p.hash))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":        p.ID,
+		"conf":      conf.Exact,
+		"origin_id": p.OriginID,
+	})
+}
+
+func (s *Server) deleteProfile(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.store.mu.Lock()
+	_, found := s.store.profiles[id]
+	delete(s.store.profiles, id)
+	s.store.mu.Unlock()
+	if !found {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) reinitProfile(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	photo, ok := formPhoto(w, r, "photo")
+	if !ok {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	p, found := s.store.profiles[id]
+	if !found {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	p.hash = photoHash(photo)
+	p.Conf = conf.Reinit
+	writeJSON(w, http.StatusOK, []map[string]interface{}{{"id": p.ID, "conf": conf.Reinit}})
+}
+
+// Entries
+
+func (s *Server) listEntries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	origins := intSet(q.Get("origin_ids"))
+	persons := stringSet(q.Get("person_ids"))
+	confs := intSet(q.Get("conf"))
+	from := parseTime(q.Get("date_from"))
+	to := parseTime(q.Get("date_to"))
+
+	s.store.mu.Lock()
+	all := s.store.sortedEntries()
+	s.store.mu.Unlock()
+
+	items := all[:0:0]
+	for _, e := range all {
+		if origins != nil && !origins[e.OriginID] ||
+			persons != nil && !persons[e.PersonID] ||
+			confs != nil && !confs[int(e.Conf)] ||
+			!from.IsZero() && e.Created.Before(from) ||
+			!to.IsZero() && e.Created.After(to) {
+			continue
+		}
+		items = append(items, e)
+	}
+	writeJSON(w, http.StatusOK, paginate(r, items))
+}
+
+func (s *Server) deleteEntry(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	s.store.mu.Lock()
+	_, found := s.store.entries[id]
+	delete(s.store.entries, id)
+	s.store.mu.Unlock()
+	if !found {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) statsSources(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	persons := stringSet(q.Get("person_ids"))
+	source := atoiDefault(q.Get("source"), 0)
+	live := q.Get("liveness")
+
+	s.store.mu.Lock()
+	counts := map[int]int{}
+	for _, e := range s.store.entries {
+		if persons != nil && !persons[e.PersonID] ||
+			source != 0 && e.OriginID != source ||
+			live != "" && string(e.Liveness) != live {
+			continue
+		}
+		counts[e.OriginID]++
+	}
+	type sourceStat struct {
+		ID      int    `json:"id"`
+		Name    string `json:"name"`
+		Entries int    `json:"entries"`
+	}
+	results := []sourceStat{}
+	for _, o := range s.store.sortedOrigins() {
+		if n := counts[o.ID]; n > 0 {
+			results = append(results, sourceStat{ID: o.ID, Name: o.Name, Entries: n})
+		}
+	}
+	s.store.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"results": results})
+}
+
+// Tokens
+
+type tokenKind int
+
+const (
+	accessKind tokenKind = iota
+	streamKind
+)
+
+func (s *Server) tokens(kind tokenKind) map[string]*Token {
+	if kind == streamKind {
+		return s.store.streamTokens
+	}
+	return s.store.accessTokens
+}
+
+func (s *Server) listTokens(kind tokenKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.store.mu.Lock()
+		items := sortedTokens(s.tokens(kind))
+		s.store.mu.Unlock()
+
+		if space := atoiDefault(r.URL.Query().Get("space_id"), 0); space != 0 {
+			filtered := items[:0:0]
+			for _, t := range items {
+				if t.SpaceID == space {
+					filtered = append(filtered, t)
+				}
+			}
+			items = filtered
+		}
+		writeJSON(w, http.StatusOK, paginate(r, items))
+	}
+}
+
+func (s *Server) createToken(kind tokenKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Permanent bool `json:"permanent"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+				writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+				return
+			}
+		}
+
+		s.store.mu.Lock()
+		prefix := "access"
+		if kind == streamKind {
+			prefix = "stream"
+		}
+		t := &Token{
+			Key:       fmt.Sprintf("mock-%s-%04d", prefix, s.store.nextTokenID),
+			Permanent: req.Permanent,
+			SpaceID:   1,
+			Created:   s.store.now(),
+		}
+		s.store.nextTokenID++
+		s.tokens(kind)[t.Key] = t
+		s.store.mu.Unlock()
+
+		writeJSON(w, http.StatusCreated, t)
+	}
+}
+
+func (s *Server) deleteToken(kind tokenKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		s.store.mu.Lock()
+		tokens := s.tokens(kind)
+		_, found := tokens[key]
+		delete(tokens, key)
+		s.store.mu.Unlock()
+		if !found {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Users
+
+// me answers for user 1, who owns every token; fixtures may leave it out.
+func (s *Server) me(w http.ResponseWriter, r *http.Request) {
+	s.store.mu.Lock()
+	u, found := s.store.users[1]
+	var copied User
+	if found {
+		copied = *u
+	}
+	s.store.mu.Unlock()
+	if !found {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	writeJSON(w, http.StatusOK, copied)
+}
+
+func (s *Server) statistics(w http.ResponseWriter, r *http.Request) {
+	s.store.mu.Lock()
+	stats := map[string]int{
+		"origins":  len(s.store.origins),
+		"profiles": len(s.store.profiles),
+		"entries":  len(s.store.entries),
+	}
+	s.store.mu.Unlock()
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	s.store.mu.Lock()
+	items := s.store.sortedUsers()
+	s.store.mu.Unlock()
+
+	if search := r.URL.Query().Get("q"); search != "" {
+		filtered := items[:0:0]
+		for _, u := range items {
+			if strings.Contains(u.Username, search) {
+				filtered = append(filtered, u)
+			}
+		}
+		items = filtered
+	}
+	writeJSON(w, http.StatusOK, paginate(r, items))
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	s.store.mu.Lock()
+	u, found := s.store.users[id]
+	var copied User
+	if found {
+		copied = *u
+	}
+	s.store.mu.Unlock()
+	if !found {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	writeJSON(w, http.StatusOK, copied)
+}
+
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	var patch struct {
+		Username *string `json:"username"`
+		IsActive *bool   `json:"is_active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	u, found := s.store.users[id]
+	if !found {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+	if patch.Username != nil {
+		u.Username = *patch.Username
+	}
+	if patch.IsActive != nil {
+		u.IsActive = *patch.IsActive
+	}
+	writeJSON(w, http.StatusOK, u)
+}
+
+func (s *Server) version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"version": "mock"})
+}
+
+// Utility
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) metrics(w http.ResponseWriter, r *http.Request) {
+	s.store.mu.Lock()
+	body := fmt.Sprintf("serp_origins_total %d\nserp_profiles_total %d\nserp_entries_total %d\n",
+		len(s.store.origins), len(s.store.profiles), len(s.store.entries))
+	s.store.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = io.WriteString(w, body)
+}
+
+func (s *Server) asm(w http.ResponseWriter, r *http.Request) {
+	photo, ok := formPhoto(w, r, "photo")
+	if !ok {
+		return
+	}
+	hash := photoHash(photo)
+	moods := []string{"happy", "neutral", "sad", "surprised"}
+	sex := "male"
+	if hexByte(hash, 1)%2 == 1 {
+		sex = "female"
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"age":  18 + hexByte(hash, 0)%50,
+		"sex":  sex,
+		"mood": moods[hexByte(hash, 2)%len(moods)],
+	})
+}
+
+func (s *Server) liveness(w http.ResponseWriter, r *http.Request) {
+	first, ok := formPhoto(w, r, "photo1")
+	if !ok {
+		return
+	}
+	second, ok := formPhoto(w, r, "photo2")
+	if !ok {
+		return
+	}
+	result := livenessOf(photoHash(append(append([]byte{}, first...), second...)))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"result": result})
+}
+
+func (s *Server) compare(w http.ResponseWriter, r *http.Request) {
+	first, ok := formPhoto(w, r, "photo1")
+	if !ok {
+		return
+	}
+	second, ok := formPhoto(w, r, "photo2")
+	if !ok {
+		return
+	}
+	result := conf.Nm
+	score := float64(hexByte(photoHash(append(append([]byte{}, first...), second...)), 0)%50) / 100
+	if photoHash(first) == photoHash(second) {
+		result, score = conf.Exact, 1
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"conf": result, "score": score})
+}
+
+// Request helpers
+
+func pathInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	n, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return 0, false
+	}
+	return n, true
+}
+
+// formPhoto reads an uploaded image from a multipart form. An empty upload
+// stands in for "no face on the picture".
+func formPhoto(w http.ResponseWriter, r *http.Request, field string) ([]byte, bool) {
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		writeError(w, http.StatusBadRequest, "expected multipart/form-data: "+err.Error())
+		return nil, false
+	}
+	f, _, err := r.FormFile(field)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, field+": No file was submitted.")
+		return nil, false
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, field+": "+err.Error())
+		return nil, false
+	}
+	if len(data) == 0 {
+		writeError(w, http.StatusBadRequest, field+": No face found on the image.")
+		return nil, false
+	}
+	return data, true
+}
+
+// profileID derives a UUID-shaped identifier from a photo hash.
+func profileID(hash string) string {
+	return hash[0:8] + "-" + hash[8:12] + "-" + hash[12:16] + "-" + hash[16:20] + "-" + hash[20:32]
+}
+
+func livenessOf(hash string) liveness.Liveness {
+	if hexByte(hash, 3)%4 == 0 {
+		return liveness.Failed
+	}
+	return liveness.Passed
+}
+
+func hexByte(hash string, i int) int {
+	n, _ := strconv.ParseUint(hash[i*2:i*2+2], 16, 8)
+	return int(n)
+}
+
+func intSet(list string) map[int]bool {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	set := map[int]bool{}
+	for _, part := range strings.Split(list, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			set[n] = true
+		}
+	}
+	return set
+}
+
+func stringSet(list string) map[string]bool {
+	if strings.TrimSpace(list) == "" {
+		return nil
+	}
+	set := map[string]bool{}
+	for _, part := range strings.Split(list, ",") {
+		set[strings.TrimSpace(part)] = true
+	}
+	return set
+}
+
+func parseTime(value string) time.Time {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
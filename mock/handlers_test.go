@@ -0,0 +1,43 @@
+package mock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// get sends an authenticated GET to the server and returns the status.
+func get(t *testing.T, s *Server, path, token string) int {
+	t.Helper()
+	return send(t, s, http.MethodGet, path, token, "")
+}
+
+// send sends an authenticated request with a JSON body, if any, and returns
+// the status.
+func send(t *testing.T, s *Server, method, path, token, body string) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestMeWithoutUserOne(t *testing.T) {
+	s, err := NewServer(Options{Fixtures: &Fixtures{Users: []User{{ID: 7, Username: "ops"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := get(t, s, "/v1/users/me/", DefaultAccessToken); code != http.StatusNotFound {
+		t.Errorf("status %d, want %d", code, http.StatusNotFound)
+	}
+	if code := get(t, s, "/v1/users/7/", DefaultRootToken); code != http.StatusOK {
+		t.Errorf("users/7: status %d, want %d", code, http.StatusOK)
+	}
+}
@@ -0,0 +1,229 @@
+// Package mock implements an in-memory stand-in for the SERP API so the CLI
+// can be exercised offline by pointing SERP_BASE_URL at it.
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default credentials accepted by a server created without explicit tokens.
+const (
+	DefaultAccessToken = "mock-access-token"
+	DefaultRootToken   = "mock-root-token"
+)
+
+// Options configures a mock server.
+type Options struct {
+	AccessToken string
+	RootToken   string
+	// Fixtures replaces the default seed data and adds fault injection rules.
+	Fixtures *Fixtures
+	// Now returns the timestamp stamped on created objects; defaults to a
+	// fixed instant so responses are reproducible.
+	Now func() time.Time
+}
+
+// Server is an http.Handler that emulates the SERP API endpoints used by the CLI.
+type Server struct {
+	opts    Options
+	mux     *http.ServeMux
+	store   *store
+	handler http.Handler
+}
+
+// NewServer returns a mock server seeded with a default origin and user, or
+// with the given fixtures.
+func NewServer(opts Options) (*Server, error) {
+	if opts.AccessToken == "" {
+		opts.AccessToken = DefaultAccessToken
+	}
+	if opts.RootToken == "" {
+		opts.RootToken = DefaultRootToken
+	}
+	if opts.Now == nil {
+		fixed := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		opts.Now = func() time.Time { return fixed }
+	}
+
+	s := &Server{opts: opts, mux: http.NewServeMux(), store: newStore(opts.Now)}
+	s.seed()
+	s.routes()
+	s.handler = s.mux
+	if opts.Fixtures != nil {
+		for i, fault := range opts.Fixtures.Faults {
+			if err := fault.validate(); err != nil {
+				return nil, fmt.Errorf("fault %d: %w", i+1, err)
+			}
+		}
+		if err := s.store.load(opts.Fixtures); err != nil {
+			return nil, err
+		}
+		if len(opts.Fixtures.Faults) > 0 {
+			s.handler = newFaultSet(opts.Fixtures.Faults).inject(s.mux)
+		}
+	}
+	return s, nil
+}
+
+func (s *Server) seed() {
+	st := s.store
+	st.origins[1] = &Origin{ID: 1, Name: "default", IsActive: true, MinFacesize: 80, EntryStorageDays: 30}
+	st.nextOriginID = 2
+	st.users[1] = &User{ID: 1, Username: "mock", IsActive: true, IsStaff: true}
+	st.accessTokens[s.opts.AccessToken] = &Token{Key: s.opts.AccessToken, Permanent: true, SpaceID: 1, Created: st.now()}
+}
+
+func (s *Server) routes() {
+	access := func(h http.HandlerFunc) http.Handler { return s.authorize(anyToken, h) }
+	accessOnly := func(h http.HandlerFunc) http.Handler { return s.authorize(accessToken, h) }
+	root := func(h http.HandlerFunc) http.Handler { return s.authorize(rootToken, h) }
+
+	s.mux.Handle("GET /v1/origins/{$}", access(s.listOrigins))
+	s.mux.Handle("POST /v1/origins/{$}", access(s.createOrigin))
+	s.mux.Handle("GET /v1/origins/{id}/{$}", access(s.getOrigin))
+	s.mux.Handle("PATCH /v1/origins/{id}/{$}", access(s.updateOrigin))
+	s.mux.Handle("PUT /v1/origins/{id}/{$}", access(s.updateOrigin))
+	s.mux.Handle("DELETE /v1/origins/{id}/{$}", access(s.deleteOrigin))
+
+	s.mux.Handle("POST /v1/profiles/{$}", access(s.createProfile))
+	s.mux.Handle("POST /v1/profiles/search/{$}", access(s.searchProfile))
+	s.mux.Handle("DELETE /v1/profiles/{id}/{$}", access(s.deleteProfile))
+	s.mux.Handle("POST /v1/profiles/{id}/reinit/{$}", access(s.reinitProfile))
+
+	s.mux.Handle("GET /v1/entries/{$}", access(s.listEntries))
+	s.mux.Handle("DELETE /v1/entries/{id}/{$}", access(s.deleteEntry))
+	s.mux.Handle("GET /v1/entries/stats/sources/{$}", access(s.statsSources))
+
+	s.mux.Handle("GET /v1/tokens/access/{$}", access(s.listTokens(accessKind)))
+	s.mux.Handle("POST /v1/tokens/access/{$}", access(s.createToken(accessKind)))
+	s.mux.Handle("DELETE /v1/tokens/access/{key}/{$}", access(s.deleteToken(accessKind)))
+	s.mux.Handle("GET /v1/tokens/streams/{$}", access(s.listTokens(streamKind)))
+	s.mux.Handle("POST /v1/tokens/streams/{$}", access(s.createToken(streamKind)))
+	s.mux.Handle("DELETE /v1/tokens/streams/{key}/{$}", access(s.deleteToken(streamKind)))
+
+	s.mux.Handle("GET /v1/users/me/{$}", access(s.me))
+	s.mux.Handle("GET /v1/users/statistics/{$}", access(s.statistics))
+	s.mux.Handle("GET /v1/users/{$}", root(s.listUsers))
+	s.mux.Handle("GET /v1/users/{id}/{$}", root(s.getUser))
+	s.mux.Handle("PUT /v1/users/{id}/{$}", root(s.updateUser))
+	s.mux.Handle("PATCH /v1/users/{id}/{$}", root(s.updateUser))
+	s.mux.Handle("GET /v1/version/{$}", access(s.version))
+
+	s.mux.HandleFunc("GET /v1/health/{$}", s.health)
+	s.mux.Handle("GET /v1/metrics/{$}", access(s.metrics))
+	s.mux.Handle("POST /v1/asm/{$}", access(s.asm))
+	s.mux.Handle("POST /v1/liveness/{$}", access(s.liveness))
+	s.mux.Handle("POST /v1/compare/{$}", accessOnly(s.compare))
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// tokenScope is the kind of credential an endpoint accepts.
+type tokenScope int
+
+const (
+	anyToken tokenScope = iota
+	accessToken
+	rootToken
+)
+
+// authorize checks the "Token <key>" header against the scope of the endpoint.
+func (s *Server) authorize(scope tokenScope, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Token ")
+		if !ok || key == "" {
+			writeError(w, http.StatusUnauthorized, "Authentication credentials were not provided.")
+			return
+		}
+
+		isRoot := key == s.opts.RootToken
+		if !isRoot {
+			s.store.mu.Lock()
+			_, known := s.store.accessTokens[key]
+			s.store.mu.Unlock()
+			if !known {
+				writeError(w, http.StatusUnauthorized, "Invalid token.")
+				return
+			}
+		}
+		if scope == rootToken && !isRoot || scope == accessToken && isRoot {
+			writeError(w, http.StatusForbidden, "You do not have permission to perform this action.")
+			return
+		}
+		next(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, map[string]string{"detail": detail})
+}
+
+// page is the paginated envelope returned by list endpoints.
+type page struct {
+	Count    int         `json:"count"`
+	Next     *string     `json:"next"`
+	Previous *string     `json:"previous"`
+	Results  interface{} `json:"results"`
+}
+
+const defaultPageSize = 100
+
+// paginate slices items by the limit and offset query parameters and builds
+// next/previous links relative to the request URL.
+func paginate[T any](r *http.Request, items []T) page {
+	q := r.URL.Query()
+	limit := atoiDefault(q.Get("limit"), defaultPageSize)
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	offset := atoiDefault(q.Get("offset"), 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	total := len(items)
+	start := min(offset, total)
+	end := min(offset+limit, total)
+	p := page{Count: total, Results: items[start:end]}
+	if end < total {
+		p.Next = pageLink(r, limit, end)
+	}
+	if start > 0 {
+		p.Previous = pageLink(r, limit, max(start-limit, 0))
+	}
+	return p
+}
+
+func pageLink(r *http.Request, limit, offset int) *string {
+	q := r.URL.Query()
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset))
+	u := url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path, RawQuery: q.Encode()}
+	link := u.String()
+	return &link
+}
+
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,84 @@
+package mock
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAuthorizeScopes(t *testing.T) {
+	s, err := NewServer(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{"GET", "/v1/origins/", DefaultAccessToken, http.StatusOK},
+		{"GET", "/v1/origins/", DefaultRootToken, http.StatusOK},
+		{"GET", "/v1/origins/", "", http.StatusUnauthorized},
+		{"GET", "/v1/origins/", "stolen-token", http.StatusUnauthorized},
+		{"GET", "/v1/users/", DefaultRootToken, http.StatusOK},
+		{"GET", "/v1/users/", DefaultAccessToken, http.StatusForbidden},
+		{"GET", "/v1/users/1/", DefaultAccessToken, http.StatusForbidden},
+		{"PATCH", "/v1/users/1/", DefaultAccessToken, http.StatusForbidden},
+		{"POST", "/v1/compare/", DefaultRootToken, http.StatusForbidden},
+		{"GET", "/v1/health/", "", http.StatusOK},
+		{"GET", "/v1/metrics/", "", http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		if got := send(t, s, tc.method, tc.path, tc.token, ""); got != tc.want {
+			t.Errorf("%s %s with %q: status %d, want %d", tc.method, tc.path, tc.token, got, tc.want)
+		}
+	}
+}
+
+func TestCustomTokens(t *testing.T) {
+	s, err := NewServer(Options{AccessToken: "custom-access", RootToken: "custom-root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := get(t, s, "/v1/origins/", "custom-access"); code != http.StatusOK {
+		t.Errorf("custom access token: status %d", code)
+	}
+	if code := get(t, s, "/v1/users/", "custom-root"); code != http.StatusOK {
+		t.Errorf("custom root token: status %d", code)
+	}
+	for _, token := range []string{DefaultAccessToken, DefaultRootToken} {
+		if code := get(t, s, "/v1/origins/", token); code != http.StatusUnauthorized {
+			t.Errorf("default token %s still accepted: status %d", token, code)
+		}
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	s, err := NewServer(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{"GET", "/v1/origins/", "", http.StatusOK},
+		{"GET", "/v1/origins/1/", "", http.StatusOK},
+		{"GET", "/v1/origins/99/", "", http.StatusNotFound},
+		{"POST", "/v1/origins/", `{"name":"gate"}`, http.StatusCreated},
+		{"PATCH", "/v1/origins/2/", `{"name":"back gate"}`, http.StatusOK},
+		{"DELETE", "/v1/origins/2/", "", http.StatusNoContent},
+		{"GET", "/v1/origins/2/", "", http.StatusNotFound},
+		{"GET", "/v1/entries/", "", http.StatusOK},
+		{"GET", "/v1/tokens/access/", "", http.StatusOK},
+		{"GET", "/v1/tokens/streams/", "", http.StatusOK},
+		{"GET", "/v1/users/me/", "", http.StatusOK},
+		{"GET", "/v1/users/statistics/", "", http.StatusOK},
+		{"GET", "/v1/version/", "", http.StatusOK},
+		{"GET", "/v1/unknown/", "", http.StatusNotFound},
+		{"DELETE", "/v1/users/me/", "", http.StatusMethodNotAllowed},
+	}
+	for _, tc := range tests {
+		if got := send(t, s, tc.method, tc.path, DefaultAccessToken, tc.body); got != tc.want {
+			t.Errorf("%s %s: status %d, want %d", tc.method, tc.path, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package mock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/serptech/serp-go/api/const/conf"
+	"github.com/serptech/serp-go/api/const/liveness"
+)
+
+// Origin is a camera or upload source.
+type Origin struct {
+	ID                int    `json:"id" yaml:"id"`
+	Name              string `json:"name" yaml:"name"`
+	IsActive          bool   `json:"is_active" yaml:"is_active"`
+	MinFacesize       int    `json:"min_facesize" yaml:"min_facesize"`
+	EntryStorageDays  int    `json:"entry_storage_days" yaml:"entry_storage_days"`
+	CreateMinFacesize int    `json:"create_min_facesize" yaml:"create_min_facesize"`
+	CreateHa          bool   `json:"create_ha" yaml:"create_ha"`
+	CreateJunk        bool   `json:"create_junk" yaml:"create_junk"`
+}
+
+// Profile is an enrolled person.
+type Profile struct {
+	ID       string    `json:"id" yaml:"id"`
+	OriginID int       `json:"origin_id" yaml:"origin_id"`
+	Conf     conf.Conf `json:"conf" yaml:"conf"`
+	Created  time.Time `json:"created" yaml:"created"`
+
+	hash string
+}
+
+// Entry is a single recognition event.
+type Entry struct {
+	ID       int               `json:"id" yaml:"id"`
+	Created  time.Time         `json:"created" yaml:"created"`
+	OriginID int               `json:"origin_id" yaml:"origin_id"`
+	PersonID string            `json:"person_id" yaml:"person_id"`
+	Conf     conf.Conf         `json:"conf" yaml:"conf"`
+	Liveness liveness.Liveness `json:"liveness" yaml:"liveness"`
+}
+
+// Token is an access or stream token.
+type Token struct {
+	Key       string    `json:"key" yaml:"key"`
+	Permanent bool      `json:"permanent" yaml:"permanent"`
+	SpaceID   int       `json:"space_id" yaml:"space_id"`
+	Created   time.Time `json:"created" yaml:"created"`
+}
+
+// User is a platform account.
+type User struct {
+	ID       int    `json:"id" yaml:"id"`
+	Username string `json:"username" yaml:"username"`
+	IsActive bool   `json:"is_active" yaml:"is_active"`
+	IsStaff  bool   `json:"is_staff" yaml:"is_staff"`
+}
+
+// store is the in-memory state behind the mock server.
+type store struct {
+	mu sync.Mutex
+
+	origins      map[int]*Origin
+	profiles     map[string]*Profile
+	entries      map[int]*Entry
+	accessTokens map[string]*Token
+	streamTokens map[string]*Token
+	users        map[int]*User
+
+	nextOriginID int
+	nextEntryID  int
+	nextTokenID  int
+	now          func() time.Time
+}
+
+func newStore(now func() time.Time) *store {
+	return &store{
+		origins:      map[int]*Origin{},
+		profiles:     map[string]*Profile{},
+		entries:      map[int]*Entry{},
+		accessTokens: map[string]*Token{},
+		streamTokens: map[string]*Token{},
+		users:        map[int]*User{},
+		nextOriginID: 1,
+		nextEntryID:  1,
+		nextTokenID:  1,
+		now:          now,
+	}
+}
+
+func (s *store) sortedOrigins() []*Origin {
+	out := make([]*Origin, 0, len(s.origins))
+	for _, o := range s.origins {
+		out = append(out, o)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (s *store) sortedEntries() []*Entry {
+	out := make([]*Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (s *store) sortedUsers() []*User {
+	out := make([]*User, 0, len(s.users))
+	for _, u := range s.users {
+		out = append(out, u)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func sortedTokens(tokens map[string]*Token) []*Token {
+	out := make([]*Token, 0, len(tokens))
+	for _, t := range tokens {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func (s *store) addEntry(originID int, personID string, c conf.Conf, l liveness.Liveness) *Entry {
+	e := &Entry{
+		ID:       s.nextEntryID,
+		Created:  s.now(),
+		OriginID: originID,
+		PersonID: personID,
+		Conf:     c,
+		Liveness: l,
+	}
+	s.nextEntryID++
+	s.entries[e.ID] = e
+	return e
+}
+
+func (s *store) profileByHash(hash string) *Profile {
+	for _, p := range s.profiles {
+		if p.hash == hash {
+			return p
+		}
+	}
+	return nil
+}
+
+// photoHash identifies a photo. The mock "recognizes" a face when exactly
+// the same bytes were enrolled before.
+func photoHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
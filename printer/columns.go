@@ -0,0 +1,14 @@
+package printer
+
+// defaultColumns are the columns shown for each resource in table and csv
+// output. Columns missing from a response are skipped.
+var defaultColumns = map[string][]string{
+	"entries":  {"id", "created", "origin_id", "person_id", "conf", "liveness", "age", "sex"},
+	"origins":  {"id", "name", "is_active", "min_facesize", "entry_storage_days", "create_min_facesize", "create_ha", "create_junk"},
+	"profiles": {"id", "person_id", "conf", "origin_id", "created", "liveness"},
+	"tokens":   {"key", "permanent", "space_id", "created", "expires"},
+	"users":    {"id", "username", "is_active", "is_staff", "date_joined", "last_login"},
+	"contexts": {"name", "current", "base_url", "access_token", "root_token"},
+	"auth":     {"context", "current", "base_url", "kind", "source", "identity", "error"},
+	"env":      {"name", "value", "source"},
+}
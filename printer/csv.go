@@ -0,0 +1,67 @@
+package printer
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+type csvPrinter struct {
+	opts Options
+}
+
+func (p *csvPrinter) Print(w io.Writer, data interface{}) error {
+	normalized, err := Normalize(data)
+	if err != nil {
+		return err
+	}
+	rows := Rows(normalized)
+	cols := Columns(p.opts, rows)
+	if len(cols) == 0 {
+		return nil
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(cols); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := cw.Write(Cells(row, cols)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvItemPrinter fixes the header from the first page and streams the rest.
+type csvItemPrinter struct {
+	w    io.Writer
+	opts Options
+	cw   *csv.Writer
+	cols []string
+}
+
+func (p *csvItemPrinter) WriteItems(items []interface{}) error {
+	normalized, err := Normalize(items)
+	if err != nil {
+		return err
+	}
+	rows := Rows(normalized)
+	if p.cw == nil {
+		p.cw = csv.NewWriter(p.w)
+		p.cols = Columns(p.opts, rows)
+		if err := p.cw.Write(p.cols); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		if err := p.cw.Write(Cells(row, p.cols)); err != nil {
+			return err
+		}
+	}
+	p.cw.Flush()
+	return p.cw.Error()
+}
+
+func (p *csvItemPrinter) Close() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	cliutils "github.com/serptech/serp-cli/utils"
+	"github.com/tidwall/pretty"
+)
+
+type jsonPrinter struct {
+	opts Options
+}
+
+func (p *jsonPrinter) Print(w io.Writer, data interface{}) error {
+	out, err := cliutils.GetPretty(data)
+	if err != nil {
+		return err
+	}
+	if p.opts.Color {
+		out = pretty.Color(out, nil)
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
+
+// jsonItemPrinter streams items as the elements of a single JSON array.
+type jsonItemPrinter struct {
+	w       io.Writer
+	color   bool
+	started bool
+}
+
+func (p *jsonItemPrinter) WriteItems(items []interface{}) error {
+	for _, item := range items {
+		data, err := json.MarshalIndent(item, "    ", "    ")
+		if err != nil {
+			return err
+		}
+		if p.color {
+			data = pretty.Color(data, nil)
+		}
+		sep := ",\n    "
+		if !p.started {
+			sep = "[\n    "
+			p.started = true
+		}
+		if _, err := io.WriteString(p.w, sep); err != nil {
+			return err
+		}
+		if _, err := p.w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *jsonItemPrinter) Close() error {
+	tail := "\n]\n"
+	if !p.started {
+		tail = "[]\n"
+	}
+	_, err := io.WriteString(p.w, tail)
+	return err
+}
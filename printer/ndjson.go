@@ -0,0 +1,38 @@
+package printer
+
+import (
+	"encoding/json"
+	"io"
+)
+
+type ndjsonPrinter struct{}
+
+func (p *ndjsonPrinter) Print(w io.Writer, data interface{}) error {
+	normalized, err := Normalize(data)
+	if err != nil {
+		return err
+	}
+	return writeLines(w, Rows(normalized))
+}
+
+type ndjsonItemPrinter struct {
+	w io.Writer
+}
+
+func (p *ndjsonItemPrinter) WriteItems(items []interface{}) error {
+	return writeLines(p.w, items)
+}
+
+func (p *ndjsonItemPrinter) Close() error {
+	return nil
+}
+
+func writeLines(w io.Writer, rows []interface{}) error {
+	enc := json.NewEncoder(w)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
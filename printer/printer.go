@@ -0,0 +1,303 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Format names an output format selectable with --format.
+type Format string
+
+const (
+	JSON   Format = "json"
+	Table  Format = "table"
+	YAML   Format = "yaml"
+	CSV    Format = "csv"
+	NDJSON Format = "ndjson"
+)
+
+// Formats lists every supported format in help order.
+var Formats = []Format{Table, JSON, YAML, CSV, NDJSON}
+
+// ParseFormat validates a --format value.
+func ParseFormat(value string) (Format, error) {
+	f := Format(strings.ToLower(strings.TrimSpace(value)))
+	if f == "" {
+		return JSON, nil
+	}
+	for _, known := range Formats {
+		if f == known {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q, expected one of %s", value, FormatNames())
+}
+
+// FormatNames returns the supported formats joined for help texts.
+func FormatNames() string {
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, "|")
+}
+
+// Options configure a printer.
+type Options struct {
+	Format Format
+	// Resource selects the default columns for table and csv output,
+	// e.g. "entries" or "origins".
+	Resource string
+	// Color enables ANSI colors for json output.
+	Color bool
+	// Query is a JMESPath or JSONPath expression applied before printing.
+	Query string
+	// Fields restricts every record to these fields and, for table and csv
+	// output, sets the columns.
+	Fields []string
+	// Template, when set, replaces the format printer.
+	Template *template.Template
+}
+
+// Printer renders a whole response.
+type Printer interface {
+	Print(w io.Writer, data interface{}) error
+}
+
+// ItemPrinter renders list items as they arrive, page by page.
+type ItemPrinter interface {
+	WriteItems(items []interface{}) error
+	Close() error
+}
+
+// New returns a printer for opts.Format.
+func New(opts Options) Printer {
+	p := newFormatPrinter(opts)
+	if opts.Query != "" || len(opts.Fields) > 0 {
+		return &transformPrinter{next: p, opts: opts}
+	}
+	return p
+}
+
+func newFormatPrinter(opts Options) Printer {
+	if opts.Template != nil {
+		return &templatePrinter{tmpl: opts.Template}
+	}
+	switch opts.Format {
+	case Table:
+		return &tablePrinter{opts: opts}
+	case YAML:
+		return &yamlPrinter{}
+	case CSV:
+		return &csvPrinter{opts: opts}
+	case NDJSON:
+		return &ndjsonPrinter{}
+	default:
+		return &jsonPrinter{opts: opts}
+	}
+}
+
+// NewItemPrinter returns a streaming printer writing to w.
+func NewItemPrinter(w io.Writer, opts Options) ItemPrinter {
+	if opts.Query != "" || opts.Template != nil {
+		return &bufferedItemPrinter{w: w, printer: New(opts), envelope: opts.Template != nil && opts.Query == ""}
+	}
+	if len(opts.Fields) > 0 {
+		return &fieldsItemPrinter{next: newFormatItemPrinter(w, opts), fields: opts.Fields}
+	}
+	return newFormatItemPrinter(w, opts)
+}
+
+func newFormatItemPrinter(w io.Writer, opts Options) ItemPrinter {
+	switch opts.Format {
+	case JSON, "":
+		return &jsonItemPrinter{w: w, color: opts.Color}
+	case NDJSON:
+		return &ndjsonItemPrinter{w: w}
+	case CSV:
+		return &csvItemPrinter{w: w, opts: opts}
+	default:
+		return &bufferedItemPrinter{w: w, printer: New(opts)}
+	}
+}
+
+// bufferedItemPrinter collects every item and prints them as one list on
+// Close, for formats that need to see all rows first. With envelope set the
+// list is wrapped as {"results": [...]}, the shape of a single page, so
+// templates work the same with and without --all. Queries see the bare list.
+type bufferedItemPrinter struct {
+	w        io.Writer
+	printer  Printer
+	items    []interface{}
+	envelope bool
+}
+
+func (p *bufferedItemPrinter) WriteItems(items []interface{}) error {
+	p.items = append(p.items, items...)
+	return nil
+}
+
+func (p *bufferedItemPrinter) Close() error {
+	if p.items == nil {
+		p.items = []interface{}{}
+	}
+	if p.envelope {
+		return p.printer.Print(p.w, map[string]interface{}{"results": p.items})
+	}
+	return p.printer.Print(p.w, p.items)
+}
+
+// transformPrinter applies --query and --fields before handing the result
+// to the format printer.
+type transformPrinter struct {
+	next Printer
+	opts Options
+}
+
+func (p *transformPrinter) Print(w io.Writer, data interface{}) error {
+	out, err := Query(data, p.opts.Query)
+	if err != nil {
+		return err
+	}
+	out, err = SelectFields(out, p.opts.Fields)
+	if err != nil {
+		return err
+	}
+	return p.next.Print(w, out)
+}
+
+type fieldsItemPrinter struct {
+	next   ItemPrinter
+	fields []string
+}
+
+func (p *fieldsItemPrinter) WriteItems(items []interface{}) error {
+	selected, err := SelectFields(items, p.fields)
+	if err != nil {
+		return err
+	}
+	return p.next.WriteItems(selected.([]interface{}))
+}
+
+func (p *fieldsItemPrinter) Close() error {
+	return p.next.Close()
+}
+
+// Normalize converts a typed API response into plain maps, slices and
+// json.Number values so every printer sees the same shape.
+func Normalize(data interface{}) (interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var out interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Rows extracts the records of a normalized response: the "results" of a
+// paginated envelope, the elements of a list, or the value itself.
+func Rows(data interface{}) []interface{} {
+	switch v := data.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		if results, ok := v["results"].([]interface{}); ok {
+			return results
+		}
+		return []interface{}{v}
+	case nil:
+		return nil
+	default:
+		return []interface{}{v}
+	}
+}
+
+// Columns picks the columns for rows: the selected fields if any, then the
+// resource defaults that appear in the data, otherwise every key found, "id"
+// first and the rest sorted.
+func Columns(opts Options, rows []interface{}) []string {
+	if len(opts.Fields) > 0 {
+		return opts.Fields
+	}
+
+	present := map[string]bool{}
+	for _, row := range rows {
+		if m, ok := row.(map[string]interface{}); ok {
+			for key := range m {
+				present[key] = true
+			}
+		}
+	}
+
+	var cols []string
+	for _, col := range defaultColumns[opts.Resource] {
+		if present[col] {
+			cols = append(cols, col)
+		}
+	}
+	if len(cols) > 0 {
+		return cols
+	}
+
+	for key := range present {
+		cols = append(cols, key)
+	}
+	sort.Slice(cols, func(i, j int) bool {
+		if cols[i] == "id" || cols[j] == "id" {
+			return cols[i] == "id"
+		}
+		return cols[i] < cols[j]
+	})
+	if len(cols) == 0 && len(rows) > 0 {
+		cols = []string{"value"}
+	}
+	return cols
+}
+
+// Cells renders one row as strings in column order.
+func Cells(row interface{}, cols []string) []string {
+	m, ok := row.(map[string]interface{})
+	out := make([]string, len(cols))
+	for i, col := range cols {
+		if !ok {
+			if col == "value" {
+				out[i] = cell(row)
+			}
+			continue
+		}
+		out[i] = cell(m[col])
+	}
+	return out
+}
+
+func cell(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		if val {
+			return "true"
+		}
+		return "false"
+	default:
+		raw, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(raw)
+	}
+}
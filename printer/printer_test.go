@@ -0,0 +1,150 @@
+package printer
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+var nested = map[string]interface{}{
+	"count": 2,
+	"results": []interface{}{
+		map[string]interface{}{"id": 1, "name": "Ann", "origin": map[string]interface{}{"id": 10, "name": "door"}, "tags": []string{"vip"}},
+		map[string]interface{}{"id": 2, "name": "Bob", "origin": nil, "extra": true},
+	},
+}
+
+func TestColumns(t *testing.T) {
+	rows := func(data interface{}) []interface{} {
+		normalized, err := Normalize(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Rows(normalized)
+	}
+	tests := []struct {
+		name string
+		opts Options
+		data interface{}
+		want []string
+	}{
+		{"fields", Options{Resource: "users", Fields: []string{"name", "origin.name"}}, nested, []string{"name", "origin.name"}},
+		{"resource defaults in data", Options{Resource: "origins"}, nested, []string{"id", "name"}},
+		{"every key", Options{Resource: "unknown"}, nested, []string{"id", "extra", "name", "origin", "tags"}},
+		{"no resource", Options{}, map[string]interface{}{"b": 1, "a": 2, "id": 3}, []string{"id", "a", "b"}},
+		{"resource without matches", Options{Resource: "tokens"}, nested, []string{"id", "extra", "name", "origin", "tags"}},
+		{"scalars", Options{}, []interface{}{"a", "b"}, []string{"value"}},
+		{"empty list", Options{Resource: "origins"}, []interface{}{}, nil},
+		{"empty envelope", Options{}, map[string]interface{}{"count": 0, "results": []interface{}{}}, nil},
+		{"null", Options{}, nil, nil},
+	}
+	for _, tc := range tests {
+		if got := Columns(tc.opts, rows(tc.data)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Columns = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDefaultColumns(t *testing.T) {
+	for resource, cols := range defaultColumns {
+		if len(cols) == 0 {
+			t.Errorf("resource %s has no default columns", resource)
+		}
+		seen := map[string]bool{}
+		for _, col := range cols {
+			if seen[col] {
+				t.Errorf("resource %s lists column %s twice", resource, col)
+			}
+			seen[col] = true
+		}
+	}
+}
+
+func TestPrinters(t *testing.T) {
+	empty := map[string]interface{}{"count": 0, "results": []interface{}{}}
+	tests := []struct {
+		name string
+		opts Options
+		data interface{}
+		want string
+	}{
+		{"table", Options{Format: Table}, nested,
+			"ID  EXTRA  NAME  ORIGIN                   TAGS\n" +
+				"1          Ann   {\"id\":10,\"name\":\"door\"}  [\"vip\"]\n" +
+				"2   true   Bob                            \n"},
+		{"table fields", Options{Format: Table, Fields: []string{"id", "origin.name"}}, nested,
+			"ID  ORIGIN.NAME\n1   door\n2   \n"},
+		{"table resource", Options{Format: Table, Resource: "origins"}, nested,
+			"ID  NAME\n1   Ann\n2   Bob\n"},
+		{"table empty", Options{Format: Table, Resource: "origins"}, empty, ""},
+		{"csv", Options{Format: CSV}, nested,
+			"id,extra,name,origin,tags\n" +
+				"1,,Ann,\"{\"\"id\"\":10,\"\"name\"\":\"\"door\"\"}\",\"[\"\"vip\"\"]\"\n" +
+				"2,true,Bob,,\n"},
+		{"csv fields", Options{Format: CSV, Fields: []string{"name", "origin.id"}}, nested,
+			"name,origin.id\nAnn,10\nBob,\n"},
+		{"csv single", Options{Format: CSV}, map[string]interface{}{"id": 7, "name": "Cy"},
+			"id,name\n7,Cy\n"},
+		{"csv empty", Options{Format: CSV, Resource: "origins"}, empty, ""},
+		{"ndjson", Options{Format: NDJSON}, nested,
+			`{"id":1,"name":"Ann","origin":{"id":10,"name":"door"},"tags":["vip"]}` + "\n" +
+				`{"extra":true,"id":2,"name":"Bob","origin":null}` + "\n"},
+		{"ndjson fields", Options{Format: NDJSON, Fields: []string{"origin.name"}}, nested,
+			`{"origin.name":"door"}` + "\n" + `{"origin.name":null}` + "\n"},
+		{"ndjson empty", Options{Format: NDJSON}, empty, ""},
+	}
+	for _, tc := range tests {
+		var out bytes.Buffer
+		if err := New(tc.opts).Print(&out, tc.data); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if out.String() != tc.want {
+			t.Errorf("%s: output\n%s\nwant\n%s", tc.name, out.String(), tc.want)
+		}
+	}
+}
+
+func TestItemPrinters(t *testing.T) {
+	pages := [][]interface{}{
+		{map[string]interface{}{"id": 1, "name": "Ann", "origin": map[string]interface{}{"name": "door"}}},
+		{map[string]interface{}{"id": 2, "name": "Bob", "extra": true}},
+	}
+	tests := []struct {
+		name  string
+		opts  Options
+		pages [][]interface{}
+		want  string
+	}{
+		{"csv keeps the first header", Options{Format: CSV}, pages,
+			"id,name,origin\n1,Ann,\"{\"\"name\"\":\"\"door\"\"}\"\n2,Bob,\n"},
+		{"csv fields", Options{Format: CSV, Fields: []string{"id", "origin.name"}}, pages,
+			"id,origin.name\n1,door\n2,\n"},
+		{"csv empty", Options{Format: CSV}, nil, ""},
+		{"ndjson", Options{Format: NDJSON}, pages,
+			`{"id":1,"name":"Ann","origin":{"name":"door"}}` + "\n" + `{"extra":true,"id":2,"name":"Bob"}` + "\n"},
+		{"ndjson empty", Options{Format: NDJSON}, nil, ""},
+		{"table", Options{Format: Table, Resource: "users"}, pages,
+			"ID\n1\n2\n"},
+		{"table empty", Options{Format: Table}, nil, ""},
+		{"json empty", Options{Format: JSON}, nil, "[]\n"},
+		{"query sees the list", Options{Format: JSON, Query: "[].id"}, pages, "[\n    1,\n    2\n]\n"},
+		{"template sees a page", Options{Template: template.Must(NewTemplate("{{range .results}}{{.name}};{{end}}", nil))}, pages, "Ann;Bob;"},
+	}
+	for _, tc := range tests {
+		var out bytes.Buffer
+		p := NewItemPrinter(&out, tc.opts)
+		for _, items := range tc.pages {
+			if err := p.WriteItems(items); err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+		}
+		if err := p.Close(); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if out.String() != tc.want {
+			t.Errorf("%s: output\n%s\nwant\n%s", tc.name, out.String(), tc.want)
+		}
+	}
+}
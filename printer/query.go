@@ -0,0 +1,120 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/PaesslerAG/jsonpath"
+	"github.com/jmespath/go-jmespath"
+)
+
+// Query evaluates expr against data. Expressions starting with "$" are
+// JSONPath, anything else is JMESPath.
+func Query(data interface{}, expr string) (interface{}, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return data, nil
+	}
+	plain, err := toPlain(data)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(expr, "$") {
+		out, err := jsonpath.Get(expr, plain)
+		if err != nil {
+			return nil, fmt.Errorf("jsonpath %q: %w", expr, err)
+		}
+		return out, nil
+	}
+	out, err := jmespath.Search(expr, plain)
+	if err != nil {
+		return nil, fmt.Errorf("jmespath %q: %w", expr, err)
+	}
+	return out, nil
+}
+
+// SelectFields keeps only the given fields of every record in data. Nested
+// values are addressed with dots, e.g. "origin.name".
+func SelectFields(data interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+	plain, err := toPlain(data)
+	if err != nil {
+		return nil, err
+	}
+	switch v := plain.(type) {
+	case []interface{}:
+		return selectEach(v, fields), nil
+	case map[string]interface{}:
+		if results, ok := v["results"].([]interface{}); ok {
+			out := make(map[string]interface{}, len(v))
+			for key, value := range v {
+				out[key] = value
+			}
+			out["results"] = selectEach(results, fields)
+			return out, nil
+		}
+		return selectOne(v, fields), nil
+	default:
+		return plain, nil
+	}
+}
+
+// ParseFields splits a --fields value.
+func ParseFields(value string) []string {
+	var fields []string
+	for _, f := range strings.Split(value, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func selectEach(rows []interface{}, fields []string) []interface{} {
+	out := make([]interface{}, len(rows))
+	for i, row := range rows {
+		if m, ok := row.(map[string]interface{}); ok {
+			out[i] = selectOne(m, fields)
+			continue
+		}
+		out[i] = row
+	}
+	return out
+}
+
+func selectOne(row map[string]interface{}, fields []string) map[string]interface{} {
+	out := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		out[field] = lookup(row, field)
+	}
+	return out
+}
+
+func lookup(row map[string]interface{}, path string) interface{} {
+	var cur interface{} = row
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+// toPlain converts data into maps, slices and float64 numbers, the shape
+// the query engines expect.
+func toPlain(data interface{}) (interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
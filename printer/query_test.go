@@ -0,0 +1,163 @@
+package printer
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type origin struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type person struct {
+	ID     int64   `json:"id"`
+	Name   string  `json:"name"`
+	Conf   float64 `json:"conf"`
+	Origin origin  `json:"origin"`
+}
+
+var page = map[string]interface{}{
+	"count": 2,
+	"results": []person{
+		{ID: 1, Name: "Ann", Conf: 0.97, Origin: origin{ID: 10, Name: "door"}},
+		{ID: 2, Name: "Bob", Conf: 0.5, Origin: origin{ID: 11, Name: "gate"}},
+	},
+}
+
+// decode parses want the way toPlain represents values, so expectations can
+// be written as JSON.
+func decode(t *testing.T, want string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(want), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		expr, want string
+	}{
+		{"", `{"count":2,"results":[{"id":1,"name":"Ann","conf":0.97,"origin":{"id":10,"name":"door"}},{"id":2,"name":"Bob","conf":0.5,"origin":{"id":11,"name":"gate"}}]}`},
+		{"count", `2`},
+		{"results[].name", `["Ann","Bob"]`},
+		{"results[?conf > `0.9`].origin.name", `["door"]`},
+		{"results[0].{id: id, origin: origin.name}", `{"id":1,"origin":"door"}`},
+		{"length(results)", `2`},
+		{"missing", `null`},
+		{"$.results[*].name", `["Ann","Bob"]`},
+		{"$.results[1].origin.id", `11`},
+		{"$.count", `2`},
+		{"  results[1].name  ", `"Bob"`},
+	}
+	for _, tc := range tests {
+		got, err := Query(page, tc.expr)
+		if err != nil {
+			t.Errorf("Query(%q): %v", tc.expr, err)
+			continue
+		}
+		plain, err := toPlain(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := decode(t, tc.want); !reflect.DeepEqual(plain, want) {
+			t.Errorf("Query(%q) = %#v, want %#v", tc.expr, plain, want)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		expr, want string
+	}{
+		{"results[", "jmespath"},
+		{"results[?name ==", "jmespath"},
+		{"$.results[", "jsonpath"},
+		{"$.missing", "jsonpath"},
+	}
+	for _, tc := range tests {
+		_, err := Query(page, tc.expr)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.want+" ") {
+			t.Errorf("Query(%q) error = %v, want a %s error", tc.expr, err, tc.want)
+		}
+	}
+	if _, err := Query(func() {}, "id"); err == nil {
+		t.Error("Query of a value that is not JSON succeeded")
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   interface{}
+		fields []string
+		want   string
+	}{
+		{"page", page, []string{"id", "origin.name"},
+			`{"count":2,"results":[{"id":1,"origin.name":"door"},{"id":2,"origin.name":"gate"}]}`},
+		{"list", page["results"], []string{"name", "missing", "name.first"},
+			`[{"name":"Ann","missing":null,"name.first":null},{"name":"Bob","missing":null,"name.first":null}]`},
+		{"single", person{ID: 3, Name: "Cy"}, []string{"name"}, `{"name":"Cy"}`},
+		{"scalars", []int{1, 2}, []string{"id"}, `[1,2]`},
+		{"number", 7, []string{"id"}, `7`},
+		{"no fields", page, nil,
+			`{"count":2,"results":[{"id":1,"name":"Ann","conf":0.97,"origin":{"id":10,"name":"door"}},{"id":2,"name":"Bob","conf":0.5,"origin":{"id":11,"name":"gate"}}]}`},
+	}
+	for _, tc := range tests {
+		got, err := SelectFields(tc.data, tc.fields)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		plain, err := toPlain(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := decode(t, tc.want); !reflect.DeepEqual(plain, want) {
+			t.Errorf("%s: SelectFields = %#v, want %#v", tc.name, plain, want)
+		}
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"id", []string{"id"}},
+		{" id , origin.name,,", []string{"id", "origin.name"}},
+	}
+	for _, tc := range tests {
+		if got := ParseFields(tc.in); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseFields(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestToPlain(t *testing.T) {
+	got, err := toPlain(person{ID: 42, Conf: 0.25, Origin: origin{Name: "door"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := got.(map[string]interface{})
+	if !ok {
+		t.Fatalf("toPlain = %T, want a map", got)
+	}
+	if id, ok := m["id"].(float64); !ok || id != 42 {
+		t.Errorf("id = %#v, want float64 42", m["id"])
+	}
+	if conf := m["conf"]; conf != 0.25 {
+		t.Errorf("conf = %#v, want 0.25", conf)
+	}
+	if name := m["origin"].(map[string]interface{})["name"]; name != "door" {
+		t.Errorf("origin.name = %#v", name)
+	}
+	if _, err := toPlain(make(chan int)); err == nil {
+		t.Error("toPlain of a channel succeeded")
+	}
+}
@@ -0,0 +1,42 @@
+package printer
+
+import (
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type tablePrinter struct {
+	opts Options
+}
+
+func (p *tablePrinter) Print(w io.Writer, data interface{}) error {
+	normalized, err := Normalize(data)
+	if err != nil {
+		return err
+	}
+	rows := Rows(normalized)
+	cols := Columns(p.opts, rows)
+	if len(cols) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	header := make([]string, len(cols))
+	for i, col := range cols {
+		header[i] = strings.ToUpper(col)
+	}
+	if _, err := io.WriteString(tw, strings.Join(header, "\t")+"\n"); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		cells := Cells(row, cols)
+		for i, c := range cells {
+			cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(c)
+		}
+		if _, err := io.WriteString(tw, strings.Join(cells, "\t")+"\n"); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
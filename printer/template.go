@@ -0,0 +1,91 @@
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// NewTemplate parses a --template body. Besides the built-in helpers, extra
+// funcs supplied by the caller are made available to the template.
+func NewTemplate(text string, funcs template.FuncMap) (*template.Template, error) {
+	tmpl := template.New("output").Funcs(template.FuncMap{
+		"date":       formatDate,
+		"json":       toJSON,
+		"jsonIndent": toJSONIndent,
+		"join":       join,
+	})
+	if funcs != nil {
+		tmpl = tmpl.Funcs(funcs)
+	}
+	parsed, err := tmpl.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	return parsed, nil
+}
+
+type templatePrinter struct {
+	tmpl *template.Template
+}
+
+func (p *templatePrinter) Print(w io.Writer, data interface{}) error {
+	normalized, err := Normalize(data)
+	if err != nil {
+		return err
+	}
+	return p.tmpl.Execute(w, normalized)
+}
+
+// formatDate reformats an RFC3339 timestamp, e.g. {{.created | date "2006-01-02"}}.
+func formatDate(layout string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case time.Time:
+		return v.Format(layout), nil
+	case string:
+		if v == "" {
+			return "", nil
+		}
+		for _, in := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+			if parsed, err := time.Parse(in, v); err == nil {
+				return parsed.Format(layout), nil
+			}
+		}
+		return "", fmt.Errorf("unable to parse date %q", v)
+	case json.Number:
+		secs, err := v.Int64()
+		if err != nil {
+			return "", fmt.Errorf("unable to parse date %q", v)
+		}
+		return time.Unix(secs, 0).UTC().Format(layout), nil
+	default:
+		return "", fmt.Errorf("unable to parse date %v", value)
+	}
+}
+
+func toJSON(value interface{}) (string, error) {
+	raw, err := json.Marshal(value)
+	return string(raw), err
+}
+
+func toJSONIndent(value interface{}) (string, error) {
+	raw, err := json.MarshalIndent(value, "", "    ")
+	return string(raw), err
+}
+
+func join(sep string, value interface{}) string {
+	items, ok := value.([]interface{})
+	if !ok {
+		return cell(value)
+	}
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = cell(item)
+	}
+	return strings.Join(parts, sep)
+}
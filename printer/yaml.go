@@ -0,0 +1,52 @@
+package printer
+
+import (
+	"encoding/json"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+type yamlPrinter struct{}
+
+func (p *yamlPrinter) Print(w io.Writer, data interface{}) error {
+	normalized, err := Normalize(data)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlValue(normalized)); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// yamlValue turns json.Number into native numbers, which yaml would
+// otherwise quote as strings.
+func yamlValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+		return val.String()
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = yamlValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = yamlValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,152 @@
+// Package redact masks credentials and photo data before they reach logs.
+package redact
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces a redacted value.
+const Mask = "[REDACTED]"
+
+// minSecretLen keeps short values, which would match all over a log line,
+// out of the secret registry.
+const minSecretLen = 6
+
+var (
+	mu      sync.RWMutex
+	secrets []string
+)
+
+// AddSecret registers values, such as the tokens in use, that String masks
+// wherever they appear.
+func AddSecret(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, v := range values {
+		if len(v) < minSecretLen {
+			continue
+		}
+		known := false
+		for _, s := range secrets {
+			if s == v {
+				known = true
+				break
+			}
+		}
+		if !known {
+			secrets = append(secrets, v)
+		}
+	}
+	// Longest first, so a secret containing another is masked whole.
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+}
+
+var (
+	authPattern  = regexp.MustCompile(`\b(Token|Bearer|Basic) [A-Za-z0-9._~+/=-]{8,}`)
+	photoPattern = regexp.MustCompile(`[A-Za-z0-9+/]{256,}={0,2}`)
+)
+
+// String masks registered secrets, credentials following an authorization
+// scheme and long base64 runs, which are photo payloads.
+func String(s string) string {
+	return photoPattern.ReplaceAllStringFunc(Secrets(s), func(m string) string {
+		return fmt.Sprintf("[REDACTED %d bytes of base64]", len(m))
+	})
+}
+
+// Secrets masks registered secrets and credentials following an
+// authorization scheme, but leaves photo payloads alone.
+func Secrets(s string) string {
+	mu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+	mu.RUnlock()
+	return authPattern.ReplaceAllStringFunc(s, func(m string) string {
+		scheme := strings.Fields(m)[0]
+		return scheme + " " + Mask
+	})
+}
+
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+// Header returns a copy of h with credential headers masked. The scheme of
+// an Authorization header is kept.
+func Header(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for name, values := range h {
+		masked := make([]string, len(values))
+		for i, v := range values {
+			switch {
+			case !sensitiveHeaders[http.CanonicalHeaderKey(name)]:
+				masked[i] = String(v)
+			case strings.Contains(v, " ") && strings.HasSuffix(http.CanonicalHeaderKey(name), "Authorization"):
+				masked[i] = strings.Fields(v)[0] + " " + Mask
+			default:
+				masked[i] = Mask
+			}
+		}
+		out[name] = masked
+	}
+	return out
+}
+
+// URL renders u with its password and credential-like query parameters
+// masked.
+func URL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	c := *u
+	if _, ok := c.User.Password(); ok {
+		c.User = url.UserPassword(c.User.Username(), Mask)
+	}
+	if c.RawQuery != "" {
+		q := c.Query()
+		for key := range q {
+			if SensitiveName(key) {
+				q[key] = []string{Mask}
+			}
+		}
+		c.RawQuery = q.Encode()
+	}
+	return String(c.String())
+}
+
+// Environ masks the values of variables in env, given as KEY=value, whose
+// names suggest a credential.
+func Environ(env []string) []string {
+	out := make([]string, len(env))
+	for i, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		if SensitiveName(key) && value != "" {
+			value = Mask
+		}
+		out[i] = key + "=" + String(value)
+	}
+	return out
+}
+
+// SensitiveName reports whether a variable, header or parameter name suggests
+// that its value is a credential.
+func SensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"token", "secret", "password", "passwd", "passphrase", "key", "auth", "credential"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Canceler ties every request to a shared context, typically cancelled on
+// SIGINT, so that cancelling it aborts requests in flight and any wait for a
+// retry or a rate limit slot.
+type Canceler struct {
+	next http.RoundTripper
+
+	mu  sync.Mutex
+	ctx context.Context
+}
+
+// NewCanceler wraps next.
+func NewCanceler(next http.RoundTripper) *Canceler {
+	return &Canceler{next: next}
+}
+
+// SetContext replaces the shared context; nil detaches requests from it.
+func (c *Canceler) SetContext(ctx context.Context) {
+	c.mu.Lock()
+	c.ctx = ctx
+	c.mu.Unlock()
+}
+
+// Context returns the context set with SetContext.
+func (c *Canceler) Context() context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ctx
+}
+
+func (c *Canceler) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	shared := c.ctx
+	c.mu.Unlock()
+	if shared == nil {
+		return c.next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithCancelCause(req.Context())
+	stop := context.AfterFunc(shared, func() { cancel(context.Cause(shared)) })
+	done := func() {
+		stop()
+		cancel(nil)
+	}
+	return finish(c.next.RoundTrip(req.WithContext(ctx)))(done)
+}
+
+// Timeout bounds each request attempt, including reading the response body,
+// so that a hung connection cannot block a command forever.
+type Timeout struct {
+	next http.RoundTripper
+
+	mu sync.Mutex
+	d  time.Duration
+}
+
+// NewTimeout wraps next. It does not bound requests until a timeout is set.
+func NewTimeout(next http.RoundTripper) *Timeout {
+	return &Timeout{next: next}
+}
+
+// SetTimeout bounds every request to d; zero removes the bound.
+func (t *Timeout) SetTimeout(d time.Duration) {
+	t.mu.Lock()
+	t.d = d
+	t.mu.Unlock()
+}
+
+func (t *Timeout) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	d := t.d
+	t.mu.Unlock()
+	if d <= 0 {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), d)
+	return finish(t.next.RoundTrip(req.WithContext(ctx)))(cancel)
+}
+
+// finish arranges for done to run once the exchange is over: right away when
+// the request failed, otherwise when the response body is drained or closed.
+func finish(resp *http.Response, err error) func(done func()) (*http.Response, error) {
+	return func(done func()) (*http.Response, error) {
+		if err != nil {
+			done()
+			return resp, err
+		}
+		resp.Body = onDone(resp.Body, done)
+		return resp, nil
+	}
+}
+
+// onDone wraps body so that done runs once, when it is drained or closed.
+func onDone(body io.ReadCloser, done func()) io.ReadCloser {
+	return &doneBody{ReadCloser: body, done: done}
+}
+
+type doneBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *doneBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.done)
+	}
+	return n, err
+}
+
+func (b *doneBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
@@ -0,0 +1,458 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/serptech/serp-cli/redact"
+)
+
+// HAR is an HTTP Archive 1.2 document, the subset of it the recorder writes
+// and the replayer reads.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Comment string     `json:"comment,omitempty"`
+	Entries []HAREntry `json:"entries"`
+	// Command is the command line that produced the recording, with
+	// secrets redacted.
+	Command []string `json:"_command,omitempty"`
+}
+
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+}
+
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	// Encoding is "base64" for binary bodies, which HAR has no field for.
+	Encoding string `json:"_encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// ReadHAR loads a recording written by WriteHAR or another HAR producer.
+func ReadHAR(path string) (*HAR, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var har HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &har, nil
+}
+
+// WriteHAR saves har to path as indented JSON.
+func WriteHAR(path string, har *HAR) error {
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// RecordOptions control what a recording keeps.
+type RecordOptions struct {
+	// ElideImages replaces image bodies, including photos in multipart
+	// uploads, with a note of their size.
+	ElideImages bool
+}
+
+// Recorder captures every request attempt with its response while it is
+// started. Secrets are redacted as the entries are recorded.
+type Recorder struct {
+	next http.RoundTripper
+
+	mu      sync.Mutex
+	active  bool
+	opts    RecordOptions
+	entries []HAREntry
+}
+
+// NewRecorder wraps next. It records nothing until started.
+func NewRecorder(next http.RoundTripper) *Recorder {
+	return &Recorder{next: next}
+}
+
+// Start begins a new recording.
+func (r *Recorder) Start(opts RecordOptions) {
+	r.mu.Lock()
+	r.active, r.opts, r.entries = true, opts, nil
+	r.mu.Unlock()
+}
+
+// Stop ends the recording and returns it.
+func (r *Recorder) Stop() *HAR {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	har := &HAR{Log: HARLog{Version: "1.2", Entries: r.entries}}
+	if har.Log.Entries == nil {
+		har.Log.Entries = []HAREntry{}
+	}
+	r.active, r.entries = false, nil
+	return har
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	active, opts := r.active, r.opts
+	r.mu.Unlock()
+	if !active {
+		return r.next.RoundTrip(req)
+	}
+
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = data
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	start := time.Now()
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	sent := time.Since(start)
+
+	respBody, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(respBody), errReader{readErr}))
+
+	entry := HAREntry{
+		StartedDateTime: start,
+		Time:            ms(time.Since(start)),
+		Request:         harRequest(req, reqBody, opts),
+		Response:        harResponse(resp, respBody, opts),
+		Timings:         HARTimings{Send: 0, Wait: ms(sent), Receive: ms(time.Since(start) - sent)},
+	}
+	r.mu.Lock()
+	if r.active {
+		r.entries = append(r.entries, entry)
+	}
+	r.mu.Unlock()
+	return resp, nil
+}
+
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	return 0, io.EOF
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func harRequest(req *http.Request, body []byte, opts RecordOptions) HARRequest {
+	out := HARRequest{
+		Method:      req.Method,
+		URL:         redact.URL(req.URL),
+		HTTPVersion: req.Proto,
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(req.Header),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+	}
+	if out.HTTPVersion == "" {
+		out.HTTPVersion = "HTTP/1.1"
+	}
+	if u, err := url.Parse(out.URL); err == nil {
+		out.QueryString = harValues(u.Query())
+	}
+	if body != nil {
+		contentType := req.Header.Get("Content-Type")
+		post := &HARPostData{MimeType: contentType}
+		post.Text, post.Encoding, post.Comment = harBody(contentType, body, opts)
+		out.PostData = post
+	}
+	return out
+}
+
+func harResponse(resp *http.Response, body []byte, opts RecordOptions) HARResponse {
+	contentType := resp.Header.Get("Content-Type")
+	out := HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(resp.Header),
+		Content:     HARContent{Size: int64(len(body)), MimeType: contentType},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+	}
+	out.Content.Text, out.Content.Encoding, out.Content.Comment = harBody(contentType, body, opts)
+	return out
+}
+
+func harHeaders(h http.Header) []HARNameValue {
+	return harValues(redact.Header(h))
+}
+
+// harValues flattens a header or query map in name order.
+func harValues(m map[string][]string) []HARNameValue {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := []HARNameValue{}
+	for _, name := range names {
+		for _, v := range m[name] {
+			out = append(out, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return out
+}
+
+// harBody renders a body as HAR text: secrets redacted in text, binary as
+// base64, and images elided when asked to.
+func harBody(contentType string, body []byte, opts RecordOptions) (text, encoding, comment string) {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if opts.ElideImages {
+		switch {
+		case strings.HasPrefix(mediaType, "image/"):
+			return "", "", elided(len(body), mediaType)
+		case mediaType == "multipart/form-data" && params["boundary"] != "":
+			if elidedBody, ok := elideMultipart(body, params["boundary"]); ok {
+				body = elidedBody
+				comment = "image parts elided"
+			}
+		}
+	}
+	if !utf8.Valid(body) {
+		return base64.StdEncoding.EncodeToString(body), "base64", comment
+	}
+	return redact.Secrets(string(body)), "", comment
+}
+
+func elided(n int, mediaType string) string {
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	return fmt.Sprintf("[elided %d bytes of %s]", n, mediaType)
+}
+
+// elideMultipart rewrites a multipart body with every file part replaced by
+// a note of its size.
+func elideMultipart(body []byte, boundary string) ([]byte, bool) {
+	var out bytes.Buffer
+	w := multipart.NewWriter(&out)
+	if err := w.SetBoundary(boundary); err != nil {
+		return nil, false
+	}
+	r := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := r.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, false
+		}
+		mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if part.FileName() != "" || strings.HasPrefix(mediaType, "image/") {
+			data = []byte(elided(len(data), mediaType))
+		}
+		pw, err := w.CreatePart(part.Header)
+		if err != nil {
+			return nil, false
+		}
+		pw.Write(data)
+	}
+	if err := w.Close(); err != nil {
+		return nil, false
+	}
+	return out.Bytes(), true
+}
+
+// ErrNotRecorded is returned for requests missing from the recording being
+// replayed. Retrying them is pointless.
+var ErrNotRecorded = errors.New("no recorded response")
+
+// Replayer answers requests from a recording instead of passing them on
+// while one is loaded. Requests are matched to unused entries by method,
+// path and query, in recorded order; the host is ignored so that a
+// recording replays against any base URL.
+type Replayer struct {
+	next http.RoundTripper
+
+	mu      sync.Mutex
+	entries []HAREntry
+	used    []bool
+	loaded  bool
+}
+
+// NewReplayer wraps next.
+func NewReplayer(next http.RoundTripper) *Replayer {
+	return &Replayer{next: next}
+}
+
+// Load starts answering from har; nil goes back to passing requests on.
+func (p *Replayer) Load(har *HAR) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if har == nil {
+		p.entries, p.used, p.loaded = nil, nil, false
+		return
+	}
+	p.entries = har.Log.Entries
+	p.used = make([]bool, len(p.entries))
+	p.loaded = true
+}
+
+func (p *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	p.mu.Lock()
+	if !p.loaded {
+		p.mu.Unlock()
+		return p.next.RoundTrip(req)
+	}
+	key := replayKey(req.Method, redact.URL(req.URL))
+	var entry *HAREntry
+	for i := range p.entries {
+		if !p.used[i] && replayKey(p.entries[i].Request.Method, p.entries[i].Request.URL) == key {
+			p.used[i] = true
+			entry = &p.entries[i]
+			break
+		}
+	}
+	p.mu.Unlock()
+
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("replay: %w for %s %s", ErrNotRecorded, req.Method, req.URL.RequestURI())
+	}
+	return replayResponse(req, entry.Response)
+}
+
+// replayKey identifies a request by method, path and sorted query.
+func replayKey(method, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return method + " " + rawURL
+	}
+	return method + " " + u.EscapedPath() + "?" + u.Query().Encode()
+}
+
+func replayResponse(req *http.Request, rec HARResponse) (*http.Response, error) {
+	body := []byte(rec.Content.Text)
+	if rec.Content.Encoding == "base64" {
+		decoded, err := base64.StdEncoding.DecodeString(rec.Content.Text)
+		if err != nil {
+			return nil, fmt.Errorf("replay: decode %s %s response: %w", req.Method, req.URL.Path, err)
+		}
+		body = decoded
+	}
+	header := http.Header{}
+	for _, h := range rec.Headers {
+		if strings.EqualFold(h.Name, "Content-Length") {
+			continue
+		}
+		header.Add(h.Name, h.Value)
+	}
+	status := rec.StatusText
+	if status == "" {
+		status = http.StatusText(rec.Status)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, status),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
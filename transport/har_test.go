@@ -0,0 +1,79 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/serptech/serp-cli/redact"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	redact.AddSecret("recorded-token")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	recorder := NewRecorder(http.DefaultTransport)
+	recorder.Start(RecordOptions{})
+	client := &http.Client{Transport: recorder}
+	for _, path := range []string{"/v1/origins/?limit=1", "/v1/users/me/"} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("Authorization", "Token recorded-token")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	har := recorder.Stop()
+	if len(har.Log.Entries) != 2 {
+		t.Fatalf("recorded %d entries, want 2", len(har.Log.Entries))
+	}
+	for _, h := range har.Log.Entries[0].Request.Headers {
+		if strings.Contains(h.Value, "recorded-token") {
+			t.Errorf("token recorded in header %s", h.Name)
+		}
+	}
+
+	replayer := NewReplayer(failingTransport{t})
+	replayer.Load(har)
+	client = &http.Client{Transport: replayer}
+	resp, err := client.Get("http://elsewhere.example/v1/users/me/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != `{"path":"/v1/users/me/"}` || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("replayed %q with %v", body, resp.Header)
+	}
+	if _, err := client.Get("http://elsewhere.example/v1/users/me/"); err == nil {
+		t.Error("replayed an entry twice")
+	}
+}
+
+func TestElideImages(t *testing.T) {
+	body := "--b\r\nContent-Disposition: form-data; name=\"origin_id\"\r\n\r\n1\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"photo\"; filename=\"a.jpg\"\r\nContent-Type: image/jpeg\r\n\r\n\xff\xd8\xff\xe0\r\n--b--\r\n"
+	text, encoding, _ := harBody("multipart/form-data; boundary=b", []byte(body), RecordOptions{ElideImages: true})
+	if encoding != "" || !strings.Contains(text, "[elided 4 bytes of image/jpeg]") || !strings.Contains(text, "\r\n\r\n1\r\n") {
+		t.Errorf("got %q (%s)", text, encoding)
+	}
+
+	text, encoding, _ = harBody("image/jpeg", []byte("\xff\xd8"), RecordOptions{})
+	if encoding != "base64" || text != "/9g=" {
+		t.Errorf("got %q (%s)", text, encoding)
+	}
+}
+
+type failingTransport struct{ t *testing.T }
+
+func (f failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.t.Errorf("request %s %s reached the network", req.Method, req.URL)
+	return nil, io.EOF
+}
@@ -0,0 +1,17 @@
+package transport
+
+import (
+	"net/http"
+	"sync"
+)
+
+var installOnce sync.Once
+
+// Install puts wrap(base) in place of http.DefaultTransport, which is what
+// the serp-go client sends its requests through. It only takes effect on
+// the first call.
+func Install(wrap func(base http.RoundTripper) http.RoundTripper) {
+	installOnce.Do(func() {
+		http.DefaultTransport = wrap(http.DefaultTransport)
+	})
+}
@@ -0,0 +1,121 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter spaces requests evenly to stay within a request rate and caps the
+// number of requests in flight. A request counts as in flight until its
+// response body is closed or fully read.
+type Limiter struct {
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	interval time.Duration
+	nextSlot time.Time
+	slots    chan struct{}
+}
+
+// NewLimiter wraps next. It does not limit until a rate or concurrency is set.
+func NewLimiter(next http.RoundTripper) *Limiter {
+	return &Limiter{next: next}
+}
+
+// SetRate allows one request per interval; zero removes the limit.
+func (l *Limiter) SetRate(interval time.Duration) {
+	l.mu.Lock()
+	l.interval = interval
+	l.nextSlot = time.Time{}
+	l.mu.Unlock()
+}
+
+// SetConcurrency allows at most n requests in flight; zero removes the limit.
+func (l *Limiter) SetConcurrency(n int) {
+	l.mu.Lock()
+	l.slots = nil
+	if n > 0 {
+		l.slots = make(chan struct{}, n)
+	}
+	l.mu.Unlock()
+}
+
+func (l *Limiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	l.mu.Lock()
+	slots := l.slots
+	var wait time.Duration
+	if l.interval > 0 {
+		now := time.Now()
+		if l.nextSlot.Before(now) {
+			l.nextSlot = now
+		}
+		wait = l.nextSlot.Sub(now)
+		l.nextSlot = l.nextSlot.Add(l.interval)
+	}
+	l.mu.Unlock()
+
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	release := func() {
+		if slots != nil {
+			<-slots
+		}
+	}
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			release()
+			return nil, req.Context().Err()
+		}
+	}
+
+	resp, err := l.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return resp, err
+	}
+	if slots != nil {
+		resp.Body = onDone(resp.Body, release)
+	}
+	return resp, nil
+}
+
+// ParseRate parses a request rate such as "20/s", "600/m", "5/2s" or a bare
+// "20" (per second) into the interval between two requests.
+func ParseRate(value string) (time.Duration, error) {
+	count, per, found := strings.Cut(strings.TrimSpace(value), "/")
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid rate %q, expected e.g. 20/s", value)
+	}
+
+	window := time.Second
+	if found {
+		switch per {
+		case "s":
+		case "m":
+			window = time.Minute
+		case "h":
+			window = time.Hour
+		default:
+			window, err = time.ParseDuration(per)
+			if err != nil || window <= 0 {
+				return 0, fmt.Errorf("invalid rate %q, expected e.g. 20/s", value)
+			}
+		}
+	}
+	return time.Duration(float64(window) / n), nil
+}
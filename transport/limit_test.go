@@ -0,0 +1,95 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"20/s", 50 * time.Millisecond},
+		{"20", 50 * time.Millisecond},
+		{"600/m", 100 * time.Millisecond},
+		{"5/2s", 400 * time.Millisecond},
+		{"3600/h", time.Second},
+	}
+	for _, tc := range tests {
+		got, err := ParseRate(tc.value)
+		if err != nil || got != tc.want {
+			t.Errorf("ParseRate(%q) = %v, %v; want %v", tc.value, got, err, tc.want)
+		}
+	}
+	for _, value := range []string{"", "0/s", "-1/s", "fast", "20/week", "20/-1s"} {
+		if _, err := ParseRate(value); err == nil {
+			t.Errorf("ParseRate(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	limiter := NewLimiter(http.DefaultTransport)
+	limiter.SetConcurrency(2)
+	client := &http.Client{Transport: limiter}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	if peak > 2 {
+		t.Errorf("%d requests in flight, want at most 2", peak)
+	}
+}
+
+func TestLimiterRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	limiter := NewLimiter(http.DefaultTransport)
+	limiter.SetRate(20 * time.Millisecond)
+	client := &http.Client{Transport: limiter}
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("5 requests took %v, want at least 80ms at one per 20ms", elapsed)
+	}
+}
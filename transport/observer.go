@@ -0,0 +1,123 @@
+// Package transport holds the HTTP middleware the CLI installs underneath
+// the serp-go client.
+package transport
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// maxErrorBody bounds how much of a failed response body is kept.
+const maxErrorBody = 64 * 1024
+
+// Exchange describes the outcome of an HTTP request that failed, either with
+// an error status or without reaching the server at all.
+type Exchange struct {
+	Method     string
+	URL        string
+	StatusCode int
+	RequestID  string
+	Header     http.Header
+	Body       []byte
+	Err        error
+}
+
+// ExchangeError is an error caused by a failed exchange. Requests that never
+// got a response fail with one, so the exchange travels with the error
+// through the client and can be found with errors.As.
+type ExchangeError struct {
+	Exchange *Exchange
+	Err      error
+}
+
+func (e *ExchangeError) Error() string { return e.Err.Error() }
+
+func (e *ExchangeError) Unwrap() error { return e.Err }
+
+// FailedExchange describes a response with an error status. body is the
+// response body, cut to a bounded size.
+func FailedExchange(req *http.Request, resp *http.Response, body []byte) *Exchange {
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody]
+	}
+	return &Exchange{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		RequestID:  requestID(req, resp),
+		Header:     resp.Header.Clone(),
+		Body:       body,
+	}
+}
+
+// Observer remembers the most recent failed exchange, which carries details
+// such as the request ID that the client leaves out of its errors. A
+// successful response clears it.
+type Observer struct {
+	next http.RoundTripper
+
+	mu   sync.Mutex
+	last *Exchange
+}
+
+// NewObserver wraps next.
+func NewObserver(next http.RoundTripper) *Observer {
+	return &Observer{next: next}
+}
+
+func (o *Observer) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := o.next.RoundTrip(req)
+	if err != nil {
+		e := &Exchange{Method: req.Method, URL: req.URL.String(), RequestID: requestID(req, nil), Err: err}
+		o.set(e)
+		return resp, &ExchangeError{Exchange: e, Err: err}
+	}
+	if resp.StatusCode < http.StatusBadRequest {
+		o.set(nil)
+		return resp, nil
+	}
+
+	body, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if readErr != nil {
+		return resp, readErr
+	}
+	o.set(FailedExchange(req, resp, body))
+	return resp, nil
+}
+
+// LastFailure returns the most recent failed exchange, if the latest request
+// failed.
+func (o *Observer) LastFailure() *Exchange {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.last
+}
+
+// Reset forgets any recorded failure.
+func (o *Observer) Reset() {
+	o.set(nil)
+}
+
+func (o *Observer) set(e *Exchange) {
+	o.mu.Lock()
+	o.last = e
+	o.mu.Unlock()
+}
+
+func requestID(req *http.Request, resp *http.Response) string {
+	for _, name := range []string{"X-Request-Id", "Request-Id"} {
+		if resp != nil {
+			if id := resp.Header.Get(name); id != "" {
+				return id
+			}
+		}
+		if id := req.Header.Get(name); id != "" {
+			return id
+		}
+	}
+	return ""
+}
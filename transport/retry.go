@@ -0,0 +1,171 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// baseRetryWait is the backoff before the first retry; it doubles with every
+// further attempt.
+const baseRetryWait = 500 * time.Millisecond
+
+// RetryPolicy controls which requests are retried and for how long.
+type RetryPolicy struct {
+	// Retries is the number of attempts made after the first one.
+	Retries int
+	// MaxWait caps a single backoff. A Retry-After longer than MaxWait ends
+	// the retries instead, since retrying earlier would be refused again.
+	MaxWait time.Duration
+	// NonIdempotent also retries requests that may have side effects, such
+	// as profile creation.
+	NonIdempotent bool
+}
+
+// Retrier retries requests that failed with a network error or a transient
+// status (408, 429, 5xx gateway errors) using jittered exponential backoff.
+type Retrier struct {
+	next http.RoundTripper
+
+	mu     sync.Mutex
+	policy RetryPolicy
+
+	// OnRetry, if set, is called before waiting for the next attempt.
+	OnRetry func(req *http.Request, attempt int, wait time.Duration, reason string)
+}
+
+// NewRetrier wraps next. It does not retry until a policy is set.
+func NewRetrier(next http.RoundTripper) *Retrier {
+	return &Retrier{next: next}
+}
+
+// SetPolicy replaces the retry policy.
+func (r *Retrier) SetPolicy(p RetryPolicy) {
+	r.mu.Lock()
+	r.policy = p
+	r.mu.Unlock()
+}
+
+func (r *Retrier) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	policy := r.policy
+	r.mu.Unlock()
+
+	if policy.Retries <= 0 || !policy.NonIdempotent && !idempotent(req) || req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return r.next.RoundTrip(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := r.next.RoundTrip(req)
+		if attempt > policy.Retries {
+			return resp, err
+		}
+
+		var reason string
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if req.Context().Err() != nil || errors.Is(err, ErrNotRecorded) {
+				return resp, err
+			}
+			reason = err.Error()
+		case RetryableStatus(resp.StatusCode):
+			reason = resp.Status
+			if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				if policy.MaxWait > 0 && after > policy.MaxWait {
+					return resp, nil
+				}
+				wait = after
+			}
+		default:
+			return resp, nil
+		}
+		if wait == 0 {
+			wait = backoff(attempt, policy.MaxWait)
+		}
+
+		body, bodyErr := rewind(req)
+		if bodyErr != nil {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+			resp.Body.Close()
+		}
+		if r.OnRetry != nil {
+			r.OnRetry(req, attempt, wait, reason)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+		if body != nil {
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// idempotent reports whether req can be repeated safely: reads, and POSTs to
+// search endpoints, which only look data up.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		return strings.HasSuffix(strings.TrimSuffix(req.URL.Path, "/"), "/search")
+	}
+	return false
+}
+
+// RetryableStatus reports whether a response status is worth retrying.
+func RetryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// backoff returns a wait between half and all of base*2^(attempt-1), capped
+// at maxWait.
+func backoff(attempt int, maxWait time.Duration) time.Duration {
+	wait := baseRetryWait << min(attempt-1, 16)
+	if maxWait > 0 && wait > maxWait {
+		wait = maxWait
+	}
+	half := wait / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// rewind returns a fresh copy of the request body for the next attempt.
+func rewind(req *http.Request) (io.ReadCloser, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	return req.GetBody()
+}
@@ -0,0 +1,92 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"0", 0, true},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, tc := range tests {
+		got, ok := retryAfter(tc.value)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tc.value, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestBackoffBounds(t *testing.T) {
+	for attempt := 1; attempt <= 40; attempt++ {
+		wait := backoff(attempt, 4*time.Second)
+		full := min(baseRetryWait<<min(attempt-1, 16), 4*time.Second)
+		if wait < full/2 || wait > full {
+			t.Errorf("backoff(%d) = %v, want within [%v, %v]", attempt, wait, full/2, full)
+		}
+	}
+}
+
+func TestRetrierReplaysBody(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := new(strings.Builder)
+		_, _ = io.Copy(buf, r.Body)
+		bodies = append(bodies, buf.String())
+		if len(bodies) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	retrier := NewRetrier(http.DefaultTransport)
+	client := &http.Client{Transport: retrier}
+
+	resp, err := client.Post(server.URL+"/v1/profiles/", "text/plain", strings.NewReader("photo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || len(bodies) != 1 {
+		t.Fatalf("without a policy: status %d after %d attempts, want 503 after 1", resp.StatusCode, len(bodies))
+	}
+
+	bodies = nil
+	retrier.SetPolicy(RetryPolicy{Retries: 3, MaxWait: time.Millisecond})
+	resp, err = client.Post(server.URL+"/v1/profiles/", "text/plain", strings.NewReader("photo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(bodies) != 1 {
+		t.Fatalf("non-idempotent POST made %d attempts, want 1", len(bodies))
+	}
+
+	bodies = nil
+	retrier.SetPolicy(RetryPolicy{Retries: 3, MaxWait: time.Millisecond, NonIdempotent: true})
+	resp, err = client.Post(server.URL+"/v1/profiles/", "text/plain", strings.NewReader("photo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status %d, want 201", resp.StatusCode)
+	}
+	if strings.Join(bodies, ",") != "photo,photo,photo" {
+		t.Errorf("bodies = %q, want the body replayed on every attempt", bodies)
+	}
+}
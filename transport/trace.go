@@ -0,0 +1,80 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/serptech/serp-cli/redact"
+)
+
+// Trace describes a finished request attempt for debug logging. URL is
+// redacted; bodies are only measured, never recorded.
+type Trace struct {
+	Method        string
+	URL           string
+	RequestID     string
+	Status        int
+	Err           error
+	Duration      time.Duration
+	RequestBytes  int64
+	ResponseBytes int64
+}
+
+// Tracer reports every request attempt to Log once its response body has
+// been read or closed.
+type Tracer struct {
+	next http.RoundTripper
+
+	// Log receives each trace; requests are passed through untouched while
+	// it is nil.
+	Log func(Trace)
+}
+
+// NewTracer wraps next.
+func NewTracer(next http.RoundTripper) *Tracer {
+	return &Tracer{next: next}
+}
+
+func (t *Tracer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Log == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	trace := Trace{
+		Method:       req.Method,
+		URL:          redact.URL(req.URL),
+		RequestID:    requestID(req, nil),
+		RequestBytes: req.ContentLength,
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		trace.Err = err
+		trace.Duration = time.Since(start)
+		t.Log(trace)
+		return resp, err
+	}
+
+	trace.Status = resp.StatusCode
+	trace.RequestID = requestID(req, resp)
+	body := &countingBody{ReadCloser: resp.Body}
+	resp.Body = onDone(body, func() {
+		trace.Duration = time.Since(start)
+		trace.ResponseBytes = body.n.Load()
+		t.Log(trace)
+	})
+	return resp, nil
+}
+
+type countingBody struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return n, err
+}
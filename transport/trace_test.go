@@ -0,0 +1,44 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	var traces []Trace
+	tracer := NewTracer(http.DefaultTransport)
+	tracer.Log = func(tr Trace) { traces = append(traces, tr) }
+	client := &http.Client{Transport: tracer}
+
+	resp, err := client.Post(server.URL+"/v1/origins/?token=abcdefgh", "application/json", strings.NewReader(`{"name":"gate"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 0 {
+		t.Fatal("traced before the body was read")
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if len(traces) != 1 {
+		t.Fatalf("got %d traces, want 1", len(traces))
+	}
+	tr := traces[0]
+	if tr.Method != "POST" || tr.Status != http.StatusCreated || tr.RequestBytes != 15 || tr.ResponseBytes != 8 {
+		t.Errorf("unexpected trace %+v", tr)
+	}
+	if strings.Contains(tr.URL, "abcdefgh") {
+		t.Errorf("token leaked into %s", tr.URL)
+	}
+}
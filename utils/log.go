@@ -1,14 +1,37 @@
 package utils
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
+	"github.com/serptech/serp-cli/redact"
 )
 
 var log zerolog.Logger
 
+// Log formats accepted by ConfigureLog.
+const (
+	LogConsole = "console"
+	LogJSON    = "json"
+)
+
+// LogOptions select where and how the CLI logs.
+type LogOptions struct {
+	// Level is one of trace, debug, info, warn, error or disabled.
+	Level string
+	// Format is LogConsole or LogJSON.
+	Format string
+	// Out receives the log; it defaults to stderr so that logs never mix
+	// with command output.
+	Out io.Writer
+	// Fields are added to every event, e.g. the command path.
+	Fields map[string]string
+}
+
 func Debug() *zerolog.Event {
 	return log.Debug()
 }
@@ -40,12 +63,76 @@ func Printf(format string, v ...interface{}) {
 	log.Printf(format, v...)
 }
 
-func init() {
-	output := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339, NoColor: false}
-	log = zerolog.New(output).With().Timestamp().Logger()
+// ConfigureLog replaces the CLI logger. The level applies to the API client
+// as well, which logs through zerolog too. Everything written is passed
+// through redact.String first.
+func ConfigureLog(opts LogOptions) error {
+	level := zerolog.WarnLevel
+	if opts.Level != "" {
+		parsed, err := zerolog.ParseLevel(strings.ToLower(opts.Level))
+		if err != nil || parsed == zerolog.NoLevel {
+			return fmt.Errorf("unknown log level %q, expected trace, debug, info, warn, error or disabled", opts.Level)
+		}
+		level = parsed
+	}
+
+	out := opts.Out
+	if out == nil {
+		out = os.Stderr
+	}
+	out = redactingWriter{out}
+	switch opts.Format {
+	case "", LogConsole:
+		noColor := opts.Out != nil && opts.Out != io.Writer(os.Stderr)
+		out = zerolog.ConsoleWriter{Out: out, TimeFormat: time.RFC3339, NoColor: noColor}
+	case LogJSON:
+	default:
+		return fmt.Errorf("unknown log format %q, expected %s or %s", opts.Format, LogConsole, LogJSON)
+	}
+
+	ctx := zerolog.New(out).With().Timestamp()
+	for key, value := range opts.Fields {
+		ctx = ctx.Str(key, value)
+	}
+	log = ctx.Logger()
+	zerolog.SetGlobalLevel(level)
+	return nil
+}
+
+// SaveLog returns a function that puts back the current logger and level,
+// for a nested command that configures its own.
+func SaveLog() (restore func()) {
+	saved, level := log, zerolog.GlobalLevel()
+	return func() {
+		log = saved
+		zerolog.SetGlobalLevel(level)
+	}
+}
+
+// redactingWriter masks secrets in every log event.
+type redactingWriter struct {
+	w io.Writer
+}
+
+func (r redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, redact.String(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// DefaultLogOptions returns the options of the startup logger: warnings on
+// stderr, or debug with SERP_DEBUG=true.
+func DefaultLogOptions() LogOptions {
+	var opts LogOptions
 	if os.Getenv("SERP_DEBUG") == "true" {
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
-	} else {
-		zerolog.SetGlobalLevel(zerolog.WarnLevel)
+		opts.Level = zerolog.LevelDebugValue
+	}
+	return opts
+}
+
+func init() {
+	if err := ConfigureLog(DefaultLogOptions()); err != nil {
+		panic(err)
 	}
 }